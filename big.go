@@ -0,0 +1,114 @@
+package conf
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// bigFloatPrecMin is a minimal precision of `big.Float` options
+const bigFloatPrecMin = 64
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+)
+
+// decodeBig decodes numbers into `big.Int` and `big.Float` options (and pointers to them).
+// Strings are decoded into `big.Float` with precision enough to keep all specified digits
+func (s *Settings) decodeBig(f reflect.Type, t reflect.Type, v interface{}) (interface{}, error) {
+
+	bt := t
+	if bt.Kind() == reflect.Ptr {
+		bt = bt.Elem()
+	}
+
+	if bt != bigIntType && bt != bigFloatType {
+		return v, nil
+	}
+
+	var r interface{}
+
+	switch f.Kind() {
+	case reflect.String:
+		// Strings are decoded into `big.Int` with `UnmarshalText`
+		if bt == bigIntType {
+			return v, nil
+		}
+		b, err := bigFloatParse(v.(string))
+		if err != nil {
+			return v, err
+		}
+		r = b
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := reflect.ValueOf(v).Int()
+		if bt == bigIntType {
+			r = new(big.Int).SetInt64(i)
+		} else {
+			r = new(big.Float).SetInt64(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := reflect.ValueOf(v).Uint()
+		if bt == bigIntType {
+			r = new(big.Int).SetUint64(u)
+		} else {
+			r = new(big.Float).SetUint64(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		fl := reflect.ValueOf(v).Float()
+		if math.IsNaN(fl) == true || math.IsInf(fl, 0) == true {
+			return v, fmt.Errorf("value '%v' can't be decoded into %s", fl, bt)
+		}
+		// Parsed float values of too big integers have already lost its precision
+		if math.Abs(fl) > 1<<53 && fl == math.Trunc(fl) {
+			return v, fmt.Errorf("value '%v' exceeds float precision, specify it as a string", fl)
+		}
+		b, err := bigFloatParse(strconv.FormatFloat(fl, 'g', -1, 64))
+		if err != nil {
+			return v, err
+		}
+		if bt == bigIntType {
+			i, a := b.Int(nil)
+			if a != big.Exact {
+				return v, fmt.Errorf("value '%v' is not an integer", fl)
+			}
+			r = i
+		} else {
+			r = b
+		}
+	default:
+		return v, nil
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return r, nil
+	}
+
+	return reflect.ValueOf(r).Elem().Interface(), nil
+}
+
+// bigFloatParse parses `str` into `big.Float` with precision enough to keep all digits of `str`
+func bigFloatParse(str string) (*big.Float, error) {
+
+	b := new(big.Float).SetPrec(bigFloatPrec(str))
+
+	if err := b.UnmarshalText([]byte(str)); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// bigFloatPrec returns precision of `big.Float` enough to keep all digits of number `str`
+func bigFloatPrec(str string) uint {
+
+	// Every decimal digit takes less than 4 bits
+	p := uint(len(str) * 4)
+	if p < bigFloatPrecMin {
+		return bigFloatPrecMin
+	}
+
+	return p
+}
@@ -0,0 +1,70 @@
+package conf
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestBigNumbers(t *testing.T) {
+
+	type tConfOut struct {
+		Total    *big.Int   `conf:"total"`
+		Count    big.Int    `conf:"count"`
+		Rate     *big.Float `conf:"rate"`
+		Price    big.Float  `conf:"price"`
+		Reserve  *big.Int   `conf:"reserve" conf_extraopts:"default=123456789012345678901234567890"`
+		Fraction *big.Float `conf:"fraction" conf_extraopts:"default=0.000000000000000000000000001"`
+	}
+
+	d := `
+total: "123456789012345678901234567890"
+count: 42
+rate: "1.000000000000000000000000001"
+price: 0.1
+`
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, d, &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Total.Cmp(big.NewInt(math.MaxInt64)) <= 0 || c.Total.String() != "123456789012345678901234567890" {
+		t.Fatal("Incorrect loaded data: Total")
+	}
+
+	if c.Count.Int64() != 42 {
+		t.Fatal("Incorrect loaded data: Count")
+	}
+
+	if c.Rate.Text('f', 27) != "1.000000000000000000000000001" {
+		t.Fatal("Incorrect loaded data: Rate:", c.Rate.Text('f', 27))
+	}
+
+	if c.Price.Text('g', -1) != "0.1" {
+		t.Fatal("Incorrect loaded data: Price:", c.Price.Text('g', -1))
+	}
+
+	if c.Reserve.String() != "123456789012345678901234567890" {
+		t.Fatal("Incorrect loaded data: Reserve")
+	}
+
+	if c.Fraction.Text('f', 27) != "0.000000000000000000000000001" {
+		t.Fatal("Incorrect loaded data: Fraction:", c.Fraction.Text('f', 27))
+	}
+
+	// Check malformed values
+	for _, e := range []string{
+		"total: abc\n",
+		"rate: abc\n",
+		"total: 1.5\n",
+		// Unquoted number is parsed as float and has already lost its precision
+		"total: 123456789012345678901234567890\n",
+	} {
+		var m tConfOut
+		if err := testLoadYAML(t, e, &m, Settings{}); err == nil {
+			t.Fatal("Expected decode error for:", e)
+		}
+	}
+}
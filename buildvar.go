@@ -0,0 +1,33 @@
+package conf
+
+import (
+	"sync"
+)
+
+// buildVars contains registered build-time variables
+var buildVars = struct {
+	sync.RWMutex
+	m map[string]string
+}{
+	m: make(map[string]string),
+}
+
+// RegisterBuildVar registers build-time variable `name` with `value` (e.g. version set via `-ldflags "-X main.version=1.0.0"`).
+// Options with `default_build=<name>` extra option default to the value of this variable
+func RegisterBuildVar(name, value string) {
+
+	buildVars.Lock()
+	defer buildVars.Unlock()
+
+	buildVars.m[name] = value
+}
+
+// buildVarGet gets value of registered build-time variable `name`
+func buildVarGet(name string) (string, bool) {
+
+	buildVars.RLock()
+	defer buildVars.RUnlock()
+
+	v, ok := buildVars.m[name]
+	return v, ok
+}
@@ -0,0 +1,39 @@
+package conf
+
+import (
+	"testing"
+)
+
+func TestBuildVar(t *testing.T) {
+
+	RegisterBuildVar("Version", "1.2.3")
+
+	type tConfOut struct {
+		Version  string `conf:"version" conf_extraopts:"default_build=Version"`
+		Override string `conf:"override" conf_extraopts:"default_build=Version"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "override: dev\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Version != "1.2.3" {
+		t.Fatal("Incorrect loaded data: Version")
+	}
+
+	if c.Override != "dev" {
+		t.Fatal("Incorrect loaded data: Override")
+	}
+
+	type tConfUnknown struct {
+		Commit string `conf:"commit" conf_extraopts:"default_build=Commit"`
+	}
+
+	var u tConfUnknown
+
+	if err := testLoadYAML(t, "{}\n", &u, Settings{}); err == nil {
+		t.Fatal("Expected error for unregistered build variable")
+	}
+}
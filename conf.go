@@ -1,14 +1,31 @@
 package conf
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"math/big"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/mitchellh/mapstructure"
 	"gopkg.in/yaml.v2"
@@ -18,26 +35,94 @@ import (
 const (
 	ConfigTypeYAML = 0
 	ConfigTypeJSON = 1
+
+	// ConfigTypeAuto detects config type by config file extension.
+	// If file name is unknown (e.g. for `LoadBytes`) JSON and then YAML formats are tried
+	ConfigTypeAuto = 2
+
+	// ConfigTypeINI is INI format where sections are mapped to nested structs
+	ConfigTypeINI = 3
+
+	// ConfigTypeJSON5 is JSON format with `//` and `/* */` comments and trailing commas allowed
+	ConfigTypeJSON5 = 4
+
+	// ConfigTypeProperties is Java-style properties format where dotted keys are mapped to nested structs
+	ConfigTypeProperties = 5
 )
 
 const (
-	tagConfName          = "conf"
-	tagConfExtraOptsName = "conf_extraopts"
-	tagConfRequiredName  = "required"
-	tagConfDefaultName   = "default"
+	tagConfName                = "conf"
+	tagConfExtraOptsName       = "conf_extraopts"
+	tagConfRequiredName        = "required"
+	tagConfDefaultName         = "default"
+	tagConfEncryptedName       = "encrypted"
+	tagConfDefaultIfSetName    = "default_if_set"
+	tagConfStrictName          = "strict"
+	tagConfSortedByName        = "sorted_by"
+	tagConfDefaultKeyName      = "default_key"
+	tagConfNotEmptyName        = "notempty"
+	tagConfReadonlyName        = "readonly"
+	tagConfDefaultOnZeroName   = "default_on_zero"
+	tagConfDecimalName         = "decimal"
+	tagConfDeprecatedAliasName = "deprecated_alias"
+	tagConfDefaultBuildName    = "default_build"
+	tagConfEncodingName        = "encoding"
+	tagConfRegexpName          = "regexp"
+	tagConfRegexpAnyName       = "regexp_any"
+	tagConfSourcePathName      = "source_path"
+	tagConfAllocDefaultsName   = "alloc_defaults"
+	tagConfDescName            = "desc"
+	tagConfMinName             = "min"
+	tagConfMaxName             = "max"
+	tagConfClampName           = "clamp"
+	tagConfGroupName           = "group"
+	tagConfAtLeastName         = "at_least"
+	tagConfUnitName            = "unit"
+	tagConfSecretName          = "secret"
+	tagConfCSVName             = "csv"
+	tagConfEnvName             = "env"
+	tagConfMinLenName          = "minlen"
+	tagConfMaxLenName          = "maxlen"
+	tagConfDiscriminatorName   = "discriminator"
+	tagConfTrimName            = "trim"
+	tagConfLowerName           = "lower"
+	tagConfUpperName           = "upper"
+	tagConfAliasesName         = "aliases"
+	tagConfStringifyName       = "stringify"
+	tagConfRequiredUnlessName  = "required_unless"
+	tagConfSchemesName         = "schemes"
 )
 
 const (
 	regexpEnv = "ENV:(.*)"
+
+	secretPrefixDefault = "SECRET:"
+
+	// envNoPrefixMarker marks ENV variables references to be resolved without `EnvVarPrefix` (e.g. `ENV:!HOME`)
+	envNoPrefixMarker = "!"
+
+	// gzipMagic is a header of gzip-compressed data
+	gzipMagic = "\x1f\x8b"
+
+	// confPathStdin is a `ConfPath` value to read config from standard input
+	confPathStdin = "-"
+
+	maxDepthDefault = 64
 )
 
+// durationType is a type of `time.Duration` options
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// optPathIndexRegexp matches indexes of slices and maps elements within options paths
+var optPathIndexRegexp = regexp.MustCompile(`\[[^\]]*\]`)
+
 // ConfigType is a loadable config type
 type ConfigType int
 
 // Settings struct contains settings config load
 type Settings struct {
 
-	// ConfPath contains the path to config file
+	// ConfPath contains the path to config file. If it is `-` config is read from standard input
 	ConfPath string
 
 	// ConfType contains config file type (see `ConfigType` constants)
@@ -47,23 +132,245 @@ type Settings struct {
 	// (see: https://godoc.org/github.com/mitchellh/mapstructure#DecoderConfig `WeaklyTypedInput` option)
 	WeaklyTypes bool
 
+	// WeaklyTypedFields contains paths of options (e.g. `server.port`) decoded with "weak" conversions regardless of `WeaklyTypes`.
+	// Indexes of slices and maps elements are omitted (e.g. `backends.port` for options of all `backends` elements)
+	WeaklyTypedFields []string
+
+	// RequiredDefaultDeny if true required options with default values must be specified in config file anyway.
+	// Otherwise default value satisfies the requirement
+	RequiredDefaultDeny bool
+
 	// UnknownDeny if true fails with an error if config file contains fields that no matching in the result interface
 	UnknownDeny bool
 
-	md mapstructure.Metadata
+	// DisableEnv if true disables ENV variables substitution, so values like `ENV:NAME` are used literally
+	DisableEnv bool
+
+	// DuplicateKeyDeny if true config load fails when the same key is specified more than once
+	// at the same level of YAML or JSON config
+	DuplicateKeyDeny bool
+
+	// DirPattern is a pattern (see `filepath.Match`) of config files names to be read by `LoadDir`.
+	// If empty all files are read
+	DirPattern string
+
+	// DirEmptyAllow if true `LoadDir` loads config with defaults if directory contains no config files.
+	// Otherwise it fails with an error
+	DirEmptyAllow bool
+
+	// EnvVarPrefix is prepended to names of ENV variables referenced in option values (e.g. `ENV:HOST` with `DB_` prefix
+	// is resolved to the value of `DB_HOST` variable). References started with `!` (e.g. `ENV:!HOME`) are resolved without prefix
+	EnvVarPrefix string
+
+	// ValidateEnvUpfront if true checks all ENV variables referenced in config file are set before options decoding.
+	// All empty variables are reported with a single error
+	ValidateEnvUpfront bool
+
+	// DocumentIndex is an index of document to be loaded from YAML multi-document stream (the first one by default)
+	DocumentIndex int
+
+	// NestedDelimiter if set splits keys of flat config formats (INI) into nested options
+	// (e.g. with `__` delimiter key `db__host` is mapped to `db.host` option)
+	NestedDelimiter string
+
+	// DecodeHooks contains user-supplied decode hooks to convert values to custom types.
+	// Hooks are called after ENV variables substitution and before built-in conversions
+	// (see: https://godoc.org/github.com/mitchellh/mapstructure#DecodeHookFunc)
+	DecodeHooks []mapstructure.DecodeHookFunc
+
+	// DecoderConfig is called with mapstructure decoder config before options decoding to set advanced options (e.g. `ZeroFields`).
+	// Fields `TagName`, `DecodeHook`, `Result` and `Metadata` are managed by this package, overriding them is at the caller's risk
+	DecoderConfig func(c *mapstructure.DecoderConfig)
+
+	// PreDecode is called with raw config data (after includes merging and `RootKey` subtree selection) before options
+	// decoding, so raw config may be changed (e.g. to migrate old config structure). Returned map is decoded instead.
+	// Error returned by the function aborts config loading
+	PreDecode func(raw map[string]interface{}) (map[string]interface{}, error)
+
+	// SanitizeStrings if true strips zero-width characters from decoded string values,
+	// trims leading and trailing UTF-8 whitespaces and replaces non-breaking spaces with regular ones
+	SanitizeStrings bool
+
+	// RootKey is a dotted path to the config file subtree to be loaded instead of the whole config
+	// (e.g. `services.api`). Dots within keys must be escaped with backslash
+	RootKey string
+
+	// RootKeyOptional if true loads an empty config if `RootKey` is not found in config file.
+	// Otherwise load fails with an error
+	RootKeyOptional bool
+
+	// RequiredCheckFirst if true checks required options are present in config file before options decoding,
+	// so load fails fast without ENV variables substitution and decode hooks calls
+	RequiredCheckFirst bool
+
+	// URLTimeout is a timeout of config request for `LoadURL` (10s by default)
+	URLTimeout time.Duration
+
+	// URLHeaders contains additional HTTP headers of config request for `LoadURL` (e.g. `Authorization`)
+	URLHeaders map[string]string
+
+	// WatchInterval is a config file poll period for `Watcher` (1s by default)
+	WatchInterval time.Duration
+
+	// MaxDepth limits nesting depth of maps and slices in config file and nesting depth of options
+	// while default values setting and required options checks (64 by default).
+	// Protects from pathological deeply nested configs from untrusted sources and self-referential structs
+	MaxDepth int
+
+	// SecretResolver resolves references to secrets (e.g. from Vault or AWS Secrets Manager).
+	// Option values in format `<SecretPrefix>REFERENCE` are substituted with `SecretResolver(REFERENCE)` result
+	SecretResolver func(ref string) (string, error)
+
+	// SecretPrefix is a prefix of references to secrets (`SECRET:` by default)
+	SecretPrefix string
+
+	// OnDeprecated is called when a deprecated option name (see `deprecated_alias` extra option)
+	// is found in config file. Arguments are paths of the deprecated and the actual options
+	OnDeprecated func(old, new string)
+
+	// Decryptor decrypts values of string options marked with `encrypted` extra option
+	Decryptor func([]byte) ([]byte, error)
+
+	// Interpolate if true substitutes references `${dotted.path}` within string options values with values of
+	// referenced options (e.g. `log_dir: ${base_dir}/logs`) after options decoding and default values setting
+	Interpolate bool
+
+	// SchemaVersion if set is a range of supported config schema versions. Config file must contain top-level
+	// option `VersionField` with a version within the range, otherwise load fails before options decoding
+	SchemaVersion *VersionRange
+
+	// VersionField is a name of top-level option containing config schema version (`version` by default)
+	VersionField string
+
+	// IncludeKey is a config file top-level key with list of files to be included by `Load`, `LoadFS` and `LoadDir`
+	// (`include` by default). Includes are disabled if it's `-`, or if config struct has a top-level option with the same name
+	IncludeKey string
+
+	// Flags contains command-line flags overriding options values. Values of flags set in command line with names
+	// equal to options paths (see `RegisterFlags`) take precedence over config file and ENV variables
+	Flags *flag.FlagSet
+
+	md          mapstructure.Metadata
+	used        map[string]bool
+	nulls       map[string]bool
+	warnings    []string
+	sourcePath  string
+	includes    bool
+	includeFS   fs.FS
+	errsCollect bool
+	errs        []*LoadError
+	defaulted   []string
+	allocs      map[reflect.Type]bool
+	rest        map[string]interface{}
+	sources     map[string]string
+}
+
+// Validator is an interface that config structs (root or nested) may implement
+// to check invariants that can't be expressed by extra options (e.g. cross-field constraints).
+// `Validate` is called after options decoding, default values setting and all checks
+type Validator interface {
+	Validate() error
 }
 
 type defaultValue struct {
 	value string
 	isSet bool
+	tag   string
 }
 
 // Load reads config
 func Load(conf interface{}, s Settings) error {
+	return load(conf, &s)
+}
 
-	// Check `conf` is a pointer
-	if reflect.TypeOf(conf).Kind() != reflect.Ptr {
-		return fmt.Errorf("config load internal error: `conf` must be a pointer")
+// LoadContext reads config the same way as `Load`. Context `ctx` is checked before config file reading
+func LoadContext(ctx context.Context, conf interface{}, s Settings) error {
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
+
+	return load(conf, &s)
+}
+
+// LoadWithWarnings reads config the same way as `Load` and returns warnings found while loading
+// (e.g. read-only options changed from its default values)
+func LoadWithWarnings(conf interface{}, s Settings) ([]string, error) {
+	err := load(conf, &s)
+	return s.warnings, err
+}
+
+// Validate checks config specified in settings against config struct of `proto` type the same way as `Load`
+// (e.g. for pre-deploy checks). Config is loaded into a throwaway value, `proto` is not changed
+func Validate(proto interface{}, s Settings) error {
+
+	t := reflect.TypeOf(proto)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return load(reflect.New(t).Interface(), &s)
+}
+
+// Meta contains metadata of loaded config
+type Meta struct {
+
+	// Warnings contains warnings found while loading (see `LoadWithWarnings`)
+	Warnings []string
+
+	// Defaulted contains paths of options set to its default values
+	Defaulted []string
+
+	// Sources contains kinds of sources (see `Source*` constants) of options values by options paths
+	Sources map[string]string
+}
+
+// Kinds of options values sources
+const (
+	// SourceLiteral is a value specified in config file as is
+	SourceLiteral = "literal"
+
+	// SourceEnv is a value of ENV variable (referenced as `ENV:NAME` or specified by `env` extra option)
+	SourceEnv = "env"
+
+	// SourceSecret is a value obtained by `SecretResolver`
+	SourceSecret = "secret"
+
+	// SourceFlag is a value of command-line flag (see `Flags` settings field)
+	SourceFlag = "flag"
+
+	// SourceDefault is a default value of option
+	SourceDefault = "default"
+)
+
+// LoadWithMeta reads config the same way as `Load` and returns metadata of loaded config
+func LoadWithMeta(conf interface{}, s Settings) (Meta, error) {
+	err := load(conf, &s)
+	return Meta{
+		Warnings:  s.warnings,
+		Defaulted: s.defaulted,
+		Sources:   s.sources,
+	}, err
+}
+
+// load reads config file specified in settings
+func load(conf interface{}, s *Settings) error {
+
+	// Config is read from standard input, so its type can't be detected by file extension
+	if s.ConfPath == confPathStdin {
+		cfgFile, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("config error: %s", err)
+		}
+		return confRead(conf, cfgFile, s)
+	}
+
+	if s.ConfType == ConfigTypeAuto {
+		t, err := confTypeDetect(s.ConfPath)
+		if err != nil {
+			return fmt.Errorf("config error: %s", err)
+		}
+		s.ConfType = t
 	}
 
 	cfgFile, err := ioutil.ReadFile(s.ConfPath)
@@ -71,294 +378,2878 @@ func Load(conf interface{}, s Settings) error {
 		return fmt.Errorf("config error: %s", err)
 	}
 
+	s.sourcePath = s.ConfPath
+	s.includes = s.includesCheck(conf)
+
+	return confRead(conf, cfgFile, s)
+}
+
+// LoadBytes reads config from `data`. Settings field `ConfPath` is ignored
+func LoadBytes(conf interface{}, data []byte, s Settings) error {
+	return confRead(conf, data, &s)
+}
+
+// LoadFS reads config file `path` from filesystem `fsys` (e.g. `embed.FS`).
+// Settings field `ConfPath` is ignored
+func LoadFS(conf interface{}, fsys fs.FS, path string, s Settings) error {
+
+	if s.ConfType == ConfigTypeAuto {
+		t, err := confTypeDetect(path)
+		if err != nil {
+			return fmt.Errorf("config error: %s", err)
+		}
+		s.ConfType = t
+	}
+
+	cfgFile, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return fmt.Errorf("config error: %s", err)
+	}
+
+	s.sourcePath = path
+	s.includes = s.includesCheck(conf)
+	s.includeFS = fsys
+
+	return confRead(conf, cfgFile, &s)
+}
+
+// confUnmarshal unmarshals config data `cfgFile` of type `t` into raw map
+func confUnmarshal(cfgFile []byte, t ConfigType) (map[string]interface{}, error) {
+
 	rawConf := make(map[string]interface{})
 
-	switch s.ConfType {
+	switch t {
 	case ConfigTypeYAML:
 		if err := yaml.Unmarshal(cfgFile, &rawConf); err != nil {
-			return fmt.Errorf("config error: %s", err)
+			return nil, yamlParseError(err)
 		}
 	case ConfigTypeJSON:
 		if err := json.Unmarshal(cfgFile, &rawConf); err != nil {
-			return fmt.Errorf("config error: %s", err)
+			return nil, jsonParseError(cfgFile, err)
 		}
+	case ConfigTypeINI:
+		return iniUnmarshal(cfgFile)
+	case ConfigTypeProperties:
+		return propertiesUnmarshal(cfgFile)
+	case ConfigTypeJSON5:
+		d, err := jsonCommentsStrip(cfgFile)
+		if err != nil {
+			return nil, err
+		}
+		return confUnmarshal(d, ConfigTypeJSON)
+	case ConfigTypeAuto:
+		// Config type is unknown, so try JSON first and YAML otherwise
+		if r, err := confUnmarshal(cfgFile, ConfigTypeJSON); err == nil {
+			return r, nil
+		}
+		return confUnmarshal(cfgFile, ConfigTypeYAML)
 	default:
-		return fmt.Errorf("config error: unknown config type")
+		return nil, fmt.Errorf("unknown config type")
 	}
 
-	config := &mapstructure.DecoderConfig{
-		WeaklyTypedInput: s.WeaklyTypes,
-		Metadata:         &s.md,
-		DecodeHook:       s.decodeFromString,
-		Result:           conf,
-		TagName:          tagConfName,
-	}
+	return rawConf, nil
+}
 
-	decoder, err := mapstructure.NewDecoder(config)
-	if err != nil {
-		return fmt.Errorf("config error: %v", err)
-	}
+// rawUnmarshal unmarshals config data `cfgFile` of type `t` into raw map. Gzip-compressed data is decompressed.
+// Keys of flat formats (INI) are split into nested maps by `NestedDelimiter`
+func (s *Settings) rawUnmarshal(cfgFile []byte, t ConfigType) (map[string]interface{}, error) {
 
-	err = decoder.Decode(rawConf)
-	if err != nil {
-		return fmt.Errorf("config error: %v", err)
+	// Gzip-compressed config is detected by magic bytes
+	if bytes.HasPrefix(cfgFile, []byte(gzipMagic)) == true {
+		d, err := gzipDecompress(cfgFile)
+		if err != nil {
+			return nil, err
+		}
+		cfgFile = d
 	}
 
-	// Set options default values
-	if err := s.setDefaults(reflect.ValueOf(conf), "", defaultValue{"", false}); err != nil {
-		return fmt.Errorf("config error: %v", err)
+	if s.DuplicateKeyDeny == true {
+		if err := s.keysDuplicatesCheck(cfgFile, t); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.checkUsedRequredOpts(reflect.ValueOf(conf), ""); err != nil {
-		return fmt.Errorf("config error: %v", err)
+	if t == ConfigTypeYAML && s.DocumentIndex > 0 {
+		return yamlDocumentUnmarshal(cfgFile, s.DocumentIndex)
 	}
 
-	if err := s.checkUnknownOpts(); err != nil {
-		return fmt.Errorf("config error: %v", err)
+	// Only the loaded subtree of JSON config is decoded
+	if t == ConfigTypeJSON && s.RootKey != "" {
+		return s.jsonSubtreeUnmarshal(cfgFile, s.optPathSplit(s.RootKey))
 	}
 
-	return nil
-}
-
-// setDefaults sets the default values from tags.
-func (s *Settings) setDefaults(val reflect.Value, parentName string, dv defaultValue) error {
-
-	if val.Kind() == reflect.Ptr && val.IsNil() == true {
-		return nil
+	rawConf, err := confUnmarshal(cfgFile, t)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check val is pointer
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
+	if t != ConfigTypeINI || s.NestedDelimiter == "" {
+		return rawConf, nil
 	}
 
-	// Check val is writable
-	if val.CanSet() == false {
-		return fmt.Errorf("internal error, object is not writable")
-	}
+	return s.rawKeysNest(rawConf)
+}
 
-	switch val.Type().Kind() {
-	case reflect.Struct:
-		for i := 0; i < val.NumField(); i++ {
-			vf := val.Field(i)
-			tf := val.Type().Field(i)
+// rawKeysNest splits keys of raw map `raw` (and its nested maps) by `NestedDelimiter`
+// into nested maps (e.g. `db__host` into `db` map with `host` key)
+func (s *Settings) rawKeysNest(raw map[string]interface{}) (map[string]interface{}, error) {
 
-			elName := parentName
-			if elName != "" {
-				elName = strings.Join([]string{elName, s.fieldNameNormalize(tf)}, ".")
-			} else {
-				elName = s.fieldNameNormalize(tf)
-			}
+	r := make(map[string]interface{})
 
-			v, isSet := s.tagValGet(tf.Tag.Get(tagConfExtraOptsName), tagConfDefaultName)
+	for k, v := range raw {
 
-			if err := s.setDefaults(vf, elName, defaultValue{v, isSet}); err != nil {
-				return err
+		if m, ok := v.(map[string]interface{}); ok == true {
+			n, err := s.rawKeysNest(m)
+			if err != nil {
+				return nil, err
 			}
+			v = n
 		}
-	case reflect.Slice, reflect.Array:
-		for i := 0; i < val.Len(); i++ {
-			vf := val.Index(i)
 
-			elName := fmt.Sprintf("%s[%d]", parentName, i)
+		p := strings.Split(k, s.NestedDelimiter)
+		c := r
 
-			if err := s.setDefaults(vf, elName, defaultValue{"", false}); err != nil {
-				return err
+		for i, e := range p[:len(p)-1] {
+
+			if _, ok := c[e]; ok == false {
+				c[e] = make(map[string]interface{})
+			}
+
+			n, ok := c[e].(map[string]interface{})
+			if ok == false {
+				return nil, fmt.Errorf("key '%s' conflicts with key '%s'", k, strings.Join(p[:i+1], s.NestedDelimiter))
 			}
+			c = n
 		}
-	case reflect.Map:
-		for _, k := range val.MapKeys() {
-			vf := val.MapIndex(k)
 
-			// Create copy of element to make it writable
-			t := reflect.Indirect(reflect.New(vf.Type()))
-			t.Set(reflect.ValueOf(vf.Interface()))
+		l := p[len(p)-1]
 
-			elName := fmt.Sprintf("%s[%s]", parentName, k)
+		if e, ok := c[l]; ok == true {
 
-			if err := s.setDefaults(t, elName, defaultValue{"", false}); err != nil {
-				return err
+			// Both values are maps (e.g. `[db]` section and `db__host` key), so merge them
+			em, ok1 := e.(map[string]interface{})
+			vm, ok2 := v.(map[string]interface{})
+			if ok1 == false || ok2 == false {
+				return nil, fmt.Errorf("key '%s' is specified more than once", k)
 			}
 
-			val.SetMapIndex(k, t)
+			if err := s.rawKeysNestMerge(em, vm, k); err != nil {
+				return nil, err
+			}
+
+			continue
 		}
 
-	default:
+		c[l] = v
+	}
 
-		// If default value set for this element and this option not used in conf file, fill it with default value
-		if dv.isSet == true && s.optIsUsed(parentName, s.md.Keys) == false {
+	return r, nil
+}
 
-			d, err := s.convFromString(dv.value, val.Type())
-			if err != nil {
-				return err
-			}
+// rawKeysNestMerge merges nested map `src` into nested map `dst` produced from key `k`
+func (s *Settings) rawKeysNestMerge(dst, src map[string]interface{}, k string) error {
 
-			switch val.Type().Kind() {
-			case reflect.Bool:
-				val.SetBool(d.(bool))
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				val.SetInt(d.(int64))
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				val.SetUint(d.(uint64))
-			case reflect.Float32, reflect.Float64:
-				val.SetFloat(d.(float64))
-			case reflect.String:
-				val.SetString(d.(string))
-			default:
-				return fmt.Errorf("internal error, default value not available for this field type `%s`", parentName)
-			}
+	for sk, sv := range src {
+
+		dv, ok := dst[sk]
+		if ok == false {
+			dst[sk] = sv
+			continue
+		}
+
+		dm, ok1 := dv.(map[string]interface{})
+		sm, ok2 := sv.(map[string]interface{})
+		if ok1 == false || ok2 == false {
+			return fmt.Errorf("key '%s%s%s' is specified more than once", k, s.NestedDelimiter, sk)
+		}
+
+		if err := s.rawKeysNestMerge(dm, sm, k+s.NestedDelimiter+sk); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-// checkUsedRequredOpts checks that config file contains all requirement options
-func (s *Settings) checkUsedRequredOpts(val reflect.Value, parentName string) error {
+// gzipDecompress decompresses gzip-compressed data
+func gzipDecompress(data []byte) ([]byte, error) {
 
-	if val.Kind() == reflect.Ptr && val.IsNil() == true {
-		return nil
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gzip: %v", err)
 	}
+	defer r.Close()
 
-	// Check val is pointer
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
+	d, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("gzip: %v", err)
 	}
 
-	switch val.Type().Kind() {
-	case reflect.Struct:
-		for i := 0; i < val.NumField(); i++ {
-			vf := val.Field(i)
-			tf := val.Type().Field(i)
-
-			elName := parentName
-			if elName != "" {
-				elName = strings.Join([]string{elName, s.fieldNameNormalize(tf)}, ".")
-			} else {
-				elName = s.fieldNameNormalize(tf)
-			}
+	return d, nil
+}
 
-			tag := tf.Tag.Get(tagConfExtraOptsName)
+// yamlDocumentUnmarshal unmarshals document with index `idx` of YAML multi-document stream `cfgFile` into raw map
+func yamlDocumentUnmarshal(cfgFile []byte, idx int) (map[string]interface{}, error) {
 
-			if s.tagKeyCheck(tag, tagConfRequiredName) == true && s.optIsUsed(elName, s.md.Keys) == false {
-				return fmt.Errorf("required option '%s' is not specified", elName)
-			}
+	decoder := yaml.NewDecoder(bytes.NewReader(cfgFile))
 
-			if err := s.checkUsedRequredOpts(vf, elName); err != nil {
-				return err
-			}
-		}
-	case reflect.Slice, reflect.Array:
-		for i := 0; i < val.Len(); i++ {
-			vf := val.Index(i)
+	for i := 0; ; i++ {
 
-			elName := fmt.Sprintf("%s[%d]", parentName, i)
+		rawConf := make(map[string]interface{})
 
-			if err := s.checkUsedRequredOpts(vf, elName); err != nil {
-				return err
+		if err := decoder.Decode(&rawConf); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("document %d not found, stream contains %d documents", idx, i)
 			}
+			return nil, err
 		}
-	case reflect.Map:
-		for _, k := range val.MapKeys() {
-			vf := val.MapIndex(k)
-
-			elName := fmt.Sprintf("%s[%s]", parentName, k)
 
-			if err := s.checkUsedRequredOpts(vf, elName); err != nil {
-				return err
-			}
+		if i == idx {
+			return rawConf, nil
 		}
 	}
-
-	return nil
 }
 
-func (s *Settings) checkUnknownOpts() error {
-	if s.UnknownDeny == true && len(s.md.Unused) > 0 {
-		return fmt.Errorf("unknown option '%s'", s.md.Unused[0])
+// confTypeDetect detects config type by config file `path` extension
+func confTypeDetect(path string) (ConfigType, error) {
+
+	// Type of compressed file is detected by the previous extension (e.g. `conf.yml.gz`)
+	if strings.ToLower(filepath.Ext(path)) == ".gz" {
+		path = strings.TrimSuffix(path, filepath.Ext(path))
 	}
-	return nil
-}
 
-// decodeFromString decodes values from string to other types.
-// Able to use field values in format `ENV:VARIABLE_NAME` to get values from ENV variables.
-func (s *Settings) decodeFromString(f reflect.Type, t reflect.Type, v interface{}) (interface{}, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ConfigTypeYAML, nil
+	case ".json":
+		return ConfigTypeJSON, nil
+	case ".ini":
+		return ConfigTypeINI, nil
+	case ".json5":
+		return ConfigTypeJSON5, nil
+	case ".properties":
+		return ConfigTypeProperties, nil
+	}
+
+	return ConfigTypeAuto, fmt.Errorf("unable to detect config type for file '%s'", path)
+}
 
-	var str string
+// confRead decodes config data `cfgFile` into `conf`
+func confRead(conf interface{}, cfgFile []byte, s *Settings) error {
 
-	if f.Kind() != reflect.String {
-		return v, nil
+	// Check `conf` is a pointer
+	if reflect.TypeOf(conf).Kind() != reflect.Ptr {
+		return fmt.Errorf("config load internal error: `conf` must be a pointer")
 	}
 
-	var r = regexp.MustCompile(regexpEnv)
-
-	result := r.FindStringSubmatch(v.(string))
+	rawConf, err := s.rawUnmarshal(cfgFile, s.ConfType)
+	if err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
 
-	if result != nil {
-		str = os.Getenv(result[1])
-		if str == "" {
-			return v, fmt.Errorf("empty ENV variable '%s'", result[1])
+	// Merge config file over files it includes
+	if s.includes == true {
+		if rawConf, err = s.rawIncludesResolve(rawConf, s.sourcePath, []string{s.includePath(s.sourcePath, "")}); err != nil {
+			return fmt.Errorf("config error: %v", err)
 		}
-	} else {
-		str = v.(string)
 	}
 
-	return s.convFromString(str, t)
+	return confRawRead(conf, rawConf, s)
 }
 
-// convFromString converts string value to other type in accordance to `t`
-func (s *Settings) convFromString(str string, t reflect.Type) (interface{}, error) {
+// confRawRead checks raw config `rawConf` read from config data and decodes it into `conf`
+func confRawRead(conf interface{}, rawConf map[string]interface{}, s *Settings) error {
 
-	switch t.Kind() {
-	case reflect.Bool:
-		return strconv.ParseBool(str)
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return strconv.ParseInt(str, 0, t.Bits())
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return strconv.ParseUint(str, 0, t.Bits())
-	case reflect.Float32:
-		return strconv.ParseFloat(str, 32)
-	case reflect.Float64:
-		return strconv.ParseFloat(str, 64)
+	var err error
+
+	// Reject config of unsupported schema version
+	if s.SchemaVersion != nil {
+		if err := s.checkVersion(rawConf); err != nil {
+			return fmt.Errorf("config error: %v", err)
+		}
 	}
 
-	return str, nil
-}
+	if s.RootKey != "" {
+		if rawConf, err = s.rawSubtreeGet(rawConf, s.RootKey); err != nil {
+			return fmt.Errorf("config error: %v", err)
+		}
+	}
 
-// fieldNameNormalize returns either name from tag if specified, or struct field name as is
-func (s *Settings) fieldNameNormalize(tf reflect.StructField) string {
+	if s.PreDecode != nil {
+		if rawConf, err = s.PreDecode(rawConf); err != nil {
+			return fmt.Errorf("config error: %v", err)
+		}
+	}
 
-	tag := tf.Tag.Get(tagConfName)
+	if err := s.checkDepth(rawConf, 1); err != nil {
+		return fmt.Errorf("config error: %v", err)
+	}
 
-	str := s.tagValIndexGet(tag, 0)
-	if str != "" {
-		return str
+	if s.ValidateEnvUpfront == true && s.DisableEnv == false {
+		if err := s.checkEnvRefs(rawConf); err != nil {
+			return fmt.Errorf("config error: %v", err)
+		}
 	}
 
-	return tf.Name
+	return confDecode(conf, rawConf, s)
 }
 
-// optIsUsed checks that string slice `usedOpts` contains `opt`
-func (s *Settings) optIsUsed(opt string, usedOpts []string) bool {
+// confDecode decodes raw config `rawConf` into `conf`
+func confDecode(conf interface{}, rawConf map[string]interface{}, s *Settings) error {
 
-	for _, v := range usedOpts {
-		if v == opt {
-			return true
-		}
+	// Map values of deprecated options names onto actual options
+	if err := s.walkRawStructs(rawConf, reflect.TypeOf(conf), "", s.resolveAliases); err != nil {
+		return fmt.Errorf("config error: %v", err)
 	}
 
-	return false
-}
+	// Collect unmatched options into `remain` fields
+	if err := s.walkRawStructs(rawConf, reflect.TypeOf(conf), "", s.resolveRemain); err != nil {
+		return fmt.Errorf("config error: %v", err)
+	}
 
-// tagPartsMakeMap prepairs map for tag pairs
+	// Collect unmatched top-level options for `LoadSplit`
+	if s.rest != nil {
+		s.restCollect(rawConf, reflect.TypeOf(conf))
+	}
+
+	s.sources = make(map[string]string)
+
+	// Override options values with ENV variables specified in extra options
+	if s.DisableEnv == false {
+		s.envOverlay(reflect.ValueOf(rawConf), reflect.TypeOf(conf), "", make(map[reflect.Type]bool))
+	}
+
+	// Override options values with command-line flags
+	if s.Flags != nil {
+		if err := s.flagsOverlay(rawConf, reflect.TypeOf(conf)); err != nil {
+			return fmt.Errorf("config error: %v", err)
+		}
+	}
+
+	// Sources are collected before raw values are changed by ENV variables substitution
+	if err := s.sourcesCollect(rawConf, reflect.TypeOf(conf)); err != nil {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	// Required options are checked after decoding while errors collecting anyway
+	if s.RequiredCheckFirst == true && s.errsCollect == false {
+		if err := s.checkRawRequredOpts(rawConf, reflect.TypeOf(conf), ""); err != nil {
+			return fmt.Errorf("config error: %v", err)
+		}
+	}
+
+	// Check options marked as strict are decodable without weak conversions
+	if err := s.checkStrictOpts(rawConf, reflect.TypeOf(conf)); err != nil && s.errorCollect(ErrorCategoryParse, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	// Prepare raw options values in accordance with extra options
+	if err := s.prepareRawOpts(rawConf, reflect.TypeOf(conf)); err != nil && s.errorCollect(ErrorCategoryParse, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	// Decode options listed in `WeaklyTypedFields` with weak conversions
+	if err := s.prepareWeakOpts(rawConf, reflect.TypeOf(conf)); err != nil && s.errorCollect(ErrorCategoryParse, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	// Decode options of polymorphic types selected by discriminators
+	if err := s.walkRaw(rawConf, reflect.TypeOf(conf), "", s.resolveDiscriminators); err != nil && s.errorCollect(ErrorCategoryParse, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	hooks := []mapstructure.DecodeHookFunc{s.decodeRefs}
+	hooks = append(hooks, s.DecodeHooks...)
+	hooks = append(hooks, s.decodeBig, s.decodeScanner, s.decodeText, s.decodeFromString)
+
+	config := &mapstructure.DecoderConfig{
+		WeaklyTypedInput: s.WeaklyTypes,
+		Metadata:         &s.md,
+		DecodeHook:       mapstructure.ComposeDecodeHookFunc(hooks...),
+		Result:           conf,
+		TagName:          tagConfName,
+	}
+
+	if s.DecoderConfig != nil {
+		s.DecoderConfig(config)
+	}
+
+	decoder, err := mapstructure.NewDecoder(config)
+	if err != nil {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	if err := decoder.Decode(rawConf); err != nil {
+		if err = decodeErrorConvert(err); s.errorCollect(ErrorCategoryParse, err) == false {
+			return fmt.Errorf("config error: %w", err)
+		}
+	}
+
+	if err := s.usedOptsCollect(rawConf, reflect.TypeOf(conf)); err != nil {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	if s.SanitizeStrings == true {
+		s.sanitizeStrings(reflect.ValueOf(conf))
+	}
+
+	// Decrypt encrypted options values
+	if err := s.decryptOpts(reflect.ValueOf(conf)); err != nil && s.errorCollect(ErrorCategoryParse, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	// Set options default values
+	if err := s.setDefaults(reflect.ValueOf(conf), "", defaultValue{"", false, ""}, 1); err != nil && s.errorCollect(ErrorCategoryValidation, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	// Set options marked with `source_path` to config source path
+	if err := s.setSourcePath(reflect.ValueOf(conf)); err != nil && s.errorCollect(ErrorCategoryValidation, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	if err := s.checkReadonlyOpts(reflect.ValueOf(conf)); err != nil && s.errorCollect(ErrorCategoryValidation, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	// Set options default values from other options
+	if err := s.setKeyDefaults(reflect.ValueOf(conf)); err != nil && s.errorCollect(ErrorCategoryValidation, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	// Substitute references to other options within string options values
+	if s.Interpolate == true {
+		if err := s.interpolateOpts(reflect.ValueOf(conf)); err != nil && s.errorCollect(ErrorCategoryValidation, err) == false {
+			return fmt.Errorf("config error: %v", err)
+		}
+	}
+
+	if err := s.checkUsedRequredOpts(reflect.ValueOf(conf), "", 1); err != nil && s.errorCollect(ErrorCategoryRequired, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	if err := s.checkUnknownOpts(); err != nil && s.errorCollect(ErrorCategoryUnknown, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	if err := s.validateOpts(reflect.ValueOf(conf)); err != nil && s.errorCollect(ErrorCategoryValidation, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	if err := s.checkGroups(reflect.ValueOf(conf)); err != nil && s.errorCollect(ErrorCategoryValidation, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	if err := s.callValidators(reflect.ValueOf(conf)); err != nil && s.errorCollect(ErrorCategoryValidation, err) == false {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	return nil
+}
+
+// setDefaults sets the default values from tags.
+func (s *Settings) setDefaults(val reflect.Value, parentName string, dv defaultValue, depth int) error {
+
+	// Options implementing `encoding.TextUnmarshaler` get default values via `UnmarshalText`
+	if s.textUnmarshalerCheck(val.Type()) == true {
+		if dv.isSet == true {
+			return s.setTextDefault(val, parentName, dv)
+		}
+		return nil
+	}
+
+	if val.Kind() == reflect.Ptr && val.IsNil() == true {
+		return nil
+	}
+
+	// Check val is pointer
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+
+	// Check val is writable
+	if val.CanSet() == false {
+		return fmt.Errorf("internal error, object is not writable")
+	}
+
+	if err := s.depthCheck(val, parentName, depth); err != nil {
+		return err
+	}
+
+	switch val.Type().Kind() {
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			vf := val.Field(i)
+			tf := val.Type().Field(i)
+
+			elName := s.optNameJoin(parentName, tf)
+
+			tag := tf.Tag.Get(tagConfExtraOptsName)
+
+			v, isSet := s.tagValGet(tag, tagConfDefaultName)
+
+			// Option defaults to the value of registered build-time variable
+			if name, ok := s.tagValGet(tag, tagConfDefaultBuildName); ok == true {
+				if v, isSet = buildVarGet(name); isSet == false {
+					return fmt.Errorf("option '%s' default value error: build variable '%s' is not registered", elName, name)
+				}
+			}
+
+			// Bool option defaults to true if specified sibling option is used in conf file
+			if sibling, ok := s.tagValGet(tag, tagConfDefaultIfSetName); ok == true {
+
+				if vf.Kind() != reflect.Bool {
+					return fmt.Errorf("option '%s' with `%s` must be a bool", elName, tagConfDefaultIfSetName)
+				}
+
+				if s.optIsUsed(s.optPathJoin(parentName, sibling)) == true {
+					v, isSet = "true", true
+				}
+			}
+
+			// Absent nested struct is allocated to be filled with its default values.
+			// Structs of recursive types are allocated only once within the chain of allocated structs
+			if s.tagKeyCheck(tag, tagConfAllocDefaultsName) == true && vf.Kind() == reflect.Ptr && vf.IsNil() == true && s.optIsNull(elName) == false {
+				et := vf.Type().Elem()
+				if et.Kind() != reflect.Struct {
+					return fmt.Errorf("option '%s' with `%s` must be a pointer to struct", elName, tagConfAllocDefaultsName)
+				}
+				if s.allocs[et] == false && s.structHasDefaults(et, make(map[reflect.Type]bool)) == true {
+					vf.Set(reflect.New(et))
+					if s.allocs == nil {
+						s.allocs = make(map[reflect.Type]bool)
+					}
+					s.allocs[et] = true
+					err := s.setDefaults(vf, elName, defaultValue{v, isSet, tag}, depth+1)
+					delete(s.allocs, et)
+					if err != nil {
+						return err
+					}
+					continue
+				}
+			}
+
+			if err := s.setDefaults(vf, elName, defaultValue{v, isSet, tag}, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			vf := val.Index(i)
+
+			// Elements explicitly set to null in config file (e.g. for `[]*T`) are kept nil
+			if vf.Kind() == reflect.Ptr && vf.IsNil() == true {
+				continue
+			}
+
+			elName := fmt.Sprintf("%s[%d]", parentName, i)
+
+			if err := s.setDefaults(vf, elName, defaultValue{"", false, ""}, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+
+		// Map default value is a list of `key=value` pairs separated by semicolons
+		if dv.isSet == true && s.defaultIsNeeded(val, parentName, dv) == true {
+			if err := s.setMapDefault(val, parentName, dv); err != nil {
+				return err
+			}
+		}
+
+		for _, k := range val.MapKeys() {
+			vf := val.MapIndex(k)
+
+			// Create copy of element to make it writable
+			t := reflect.Indirect(reflect.New(vf.Type()))
+			t.Set(vf)
+
+			elName := fmt.Sprintf("%s[%v]", parentName, k)
+
+			if err := s.setDefaults(t, elName, defaultValue{"", false, ""}, depth+1); err != nil {
+				return err
+			}
+
+			val.SetMapIndex(k, t)
+		}
+
+	default:
+
+		// If default value set for this element and this option not used in conf file
+		// (or has zero value if `default_on_zero` is set), fill it with default value
+		if dv.isSet == true && s.defaultIsNeeded(val, parentName, dv) == true {
+
+			str, err := s.refResolve(dv.value)
+			if err != nil {
+				return fmt.Errorf("option '%s' default value error: %v", parentName, err)
+			}
+
+			d, err := s.convFromString(s.optStrNormalize(str, val.Type(), dv.tag), val.Type())
+			if err != nil {
+				return err
+			}
+
+			switch val.Type().Kind() {
+			case reflect.Bool:
+				val.SetBool(d.(bool))
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				val.SetInt(d.(int64))
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				val.SetUint(d.(uint64))
+			case reflect.Float32, reflect.Float64:
+				val.SetFloat(d.(float64))
+			case reflect.String:
+				val.SetString(d.(string))
+			default:
+				return fmt.Errorf("internal error, default value not available for this field type `%s`", parentName)
+			}
+
+			s.defaultedAdd(parentName)
+		} else if dv.isSet == true && val.Kind() == reflect.String && val.Len() == 0 && s.optIsNull(parentName) == false {
+
+			// It's ambiguous whether empty value or default value is meant
+			s.warnings = append(s.warnings, fmt.Sprintf("option '%s' is specified with empty value, so default value is not applied", parentName))
+		}
+	}
+
+	return nil
+}
+
+// setSourcePath sets string options marked with `source_path` extra option to the path of loaded config file
+func (s *Settings) setSourcePath(val reflect.Value) error {
+
+	return s.walkFields(val, "", func(vf reflect.Value, tf reflect.StructField, elName string) error {
+
+		if s.tagKeyCheck(tf.Tag.Get(tagConfExtraOptsName), tagConfSourcePathName) == false {
+			return nil
+		}
+
+		if vf.Kind() != reflect.String {
+			return fmt.Errorf("option '%s' with `%s` must be a string", elName, tagConfSourcePathName)
+		}
+
+		vf.SetString(s.sourcePath)
+
+		return nil
+	})
+}
+
+// structHasDefaults checks struct type `t` has options with default values (including nested structs)
+func (s *Settings) structHasDefaults(t reflect.Type, visited map[reflect.Type]bool) bool {
+
+	if visited[t] == true {
+		return false
+	}
+	visited[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		tf := t.Field(i)
+		tag := tf.Tag.Get(tagConfExtraOptsName)
+
+		if s.tagKeyCheck(tag, tagConfDefaultName) == true || s.tagKeyCheck(tag, tagConfDefaultBuildName) == true {
+			return true
+		}
+
+		ft := tf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct && s.textUnmarshalerCheck(ft) == false && s.structHasDefaults(ft, visited) == true {
+			return true
+		}
+	}
+
+	return false
+}
+
+// defaultIsNeeded checks that default value must be set for option `val` with path `name`.
+// Options explicitly specified with null values are kept zero unless `default_on_zero` is set
+func (s *Settings) defaultIsNeeded(val reflect.Value, name string, dv defaultValue) bool {
+
+	if s.optIsUsed(name) == false && s.optIsNull(name) == false {
+		return true
+	}
+
+	return s.tagKeyCheck(dv.tag, tagConfDefaultOnZeroName) == true && val.IsZero() == true
+}
+
+// setTextDefault sets the default value for option implementing `encoding.TextUnmarshaler`
+func (s *Settings) setTextDefault(val reflect.Value, parentName string, dv defaultValue) error {
+
+	if s.defaultIsNeeded(val, parentName, dv) == false {
+		return nil
+	}
+
+	str, err := s.refResolve(dv.value)
+	if err != nil {
+		return fmt.Errorf("option '%s' default value error: %v", parentName, err)
+	}
+
+	var u encoding.TextUnmarshaler
+
+	if val.Kind() == reflect.Ptr {
+		val.Set(reflect.New(val.Type().Elem()))
+		u = s.textUnmarshalerGet(val.Interface())
+	} else {
+		u = s.textUnmarshalerGet(val.Addr().Interface())
+	}
+
+	// Precision of `big.Float` is set to keep all digits of the default value
+	if b, ok := u.(*big.Float); ok == true {
+		b.SetPrec(bigFloatPrec(str))
+	}
+
+	if err := u.UnmarshalText([]byte(str)); err != nil {
+		return fmt.Errorf("option '%s' default value error: %v", parentName, err)
+	}
+
+	s.defaultedAdd(parentName)
+
+	return nil
+}
+
+// setMapDefault sets the default value in format `key1=value1;key2=value2` for map option
+func (s *Settings) setMapDefault(val reflect.Value, parentName string, dv defaultValue) error {
+
+	str, err := s.refResolve(dv.value)
+	if err != nil {
+		return fmt.Errorf("option '%s' default value error: %v", parentName, err)
+	}
+
+	m := reflect.MakeMap(val.Type())
+
+	for _, e := range strings.Split(str, ";") {
+
+		if strings.TrimSpace(e) == "" {
+			continue
+		}
+
+		p := strings.SplitN(e, "=", 2)
+		if len(p) != 2 {
+			return fmt.Errorf("option '%s' default value error: pair '%s' has no value", parentName, e)
+		}
+
+		k, err := s.convValue(strings.TrimSpace(p[0]), val.Type().Key())
+		if err != nil {
+			return fmt.Errorf("option '%s' default value error: %v", parentName, err)
+		}
+
+		v, err := s.convValue(strings.TrimSpace(p[1]), val.Type().Elem())
+		if err != nil {
+			return fmt.Errorf("option '%s' default value error: %v", parentName, err)
+		}
+
+		m.SetMapIndex(k, v)
+	}
+
+	val.Set(m)
+	s.defaultedAdd(parentName)
+
+	return nil
+}
+
+// defaultedAdd marks option with path `name` as set to its default value
+func (s *Settings) defaultedAdd(name string) {
+	s.defaulted = append(s.defaulted, name)
+	s.sources[name] = SourceDefault
+}
+
+// sourcesCollect collects kinds of sources of options values specified in raw config data.
+// Options overridden with ENV variables by `env` extra option are already collected by `envOverlay`
+func (s *Settings) sourcesCollect(rawConf map[string]interface{}, t reflect.Type) error {
+
+	return s.walkRaw(rawConf, t, "", func(m reflect.Value, k reflect.Value, tf reflect.StructField, elName string) error {
+
+		if _, ok := s.sources[elName]; ok == true {
+			return nil
+		}
+
+		switch v := m.MapIndex(k).Interface().(type) {
+		case nil, map[string]interface{}, map[interface{}]interface{}:
+			// Nested structs and maps are not values themselves
+		case string:
+			s.sources[elName] = s.refSourceGet(v)
+		default:
+			s.sources[elName] = SourceLiteral
+		}
+
+		return nil
+	})
+}
+
+// refSourceGet returns kind of source of value `str` specified in config file
+func (s *Settings) refSourceGet(str string) string {
+
+	if s.DisableEnv == false && regexp.MustCompile(regexpEnv).MatchString(str) == true {
+		return SourceEnv
+	}
+
+	prefix := s.SecretPrefix
+	if prefix == "" {
+		prefix = secretPrefixDefault
+	}
+
+	if s.SecretResolver != nil && strings.HasPrefix(str, prefix) == true {
+		return SourceSecret
+	}
+
+	return SourceLiteral
+}
+
+// checkReadonlyOpts adds warnings for read-only options specified in config file with values differ from its defaults
+func (s *Settings) checkReadonlyOpts(val reflect.Value) error {
+
+	return s.walkFields(val, "", func(vf reflect.Value, tf reflect.StructField, elName string) error {
+
+		tag := tf.Tag.Get(tagConfExtraOptsName)
+
+		if s.tagKeyCheck(tag, tagConfReadonlyName) == false || s.optIsUsed(elName) == false {
+			return nil
+		}
+
+		d := reflect.Zero(vf.Type())
+
+		if v, isSet := s.tagValGet(tag, tagConfDefaultName); isSet == true {
+
+			str, err := s.refResolve(v)
+			if err != nil {
+				return fmt.Errorf("option '%s' default value error: %v", elName, err)
+			}
+
+			d, err = s.convValue(str, vf.Type())
+			if err != nil {
+				return err
+			}
+		}
+
+		if reflect.DeepEqual(vf.Interface(), d.Interface()) == false {
+			s.warnings = append(s.warnings, fmt.Sprintf("read-only option '%s' is changed from its default value", elName))
+		}
+
+		return nil
+	})
+}
+
+// setKeyDefaults sets the default values from other options specified by dotted path in `default_key` tags.
+// It is called after `setDefaults`, so source options already contain their own default values.
+func (s *Settings) setKeyDefaults(val reflect.Value) error {
+
+	return s.walkFields(val, "", func(vf reflect.Value, tf reflect.StructField, elName string) error {
+
+		key, ok := s.tagValGet(tf.Tag.Get(tagConfExtraOptsName), tagConfDefaultKeyName)
+		if ok == false || s.optIsUsed(elName) == true || s.optIsNull(elName) == true {
+			return nil
+		}
+
+		src, ok := s.valueByPath(val, key)
+		if ok == false {
+			return fmt.Errorf("source option '%s' for default value of option '%s' is not found", key, elName)
+		}
+
+		if src.Type().AssignableTo(vf.Type()) == false {
+			return fmt.Errorf("source option '%s' type `%s` mismatch option '%s' type `%s`", key, src.Type(), elName, vf.Type())
+		}
+
+		vf.Set(src)
+		s.defaultedAdd(elName)
+
+		return nil
+	})
+}
+
+// checkUsedRequredOpts checks that config file contains all requirement options
+func (s *Settings) checkUsedRequredOpts(val reflect.Value, parentName string, depth int) error {
+
+	if val.Kind() == reflect.Ptr && val.IsNil() == true {
+		return nil
+	}
+
+	// Check val is pointer
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+
+	if err := s.depthCheck(val, parentName, depth); err != nil {
+		return err
+	}
+
+	switch val.Type().Kind() {
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			vf := val.Field(i)
+			tf := val.Type().Field(i)
+
+			elName := s.optNameJoin(parentName, tf)
+
+			tag := tf.Tag.Get(tagConfExtraOptsName)
+
+			required := s.tagKeyCheck(tag, tagConfRequiredName)
+
+			// Option is required unless sibling option has the specified value
+			if cond, ok := s.tagValGet(tag, tagConfRequiredUnlessName); ok == true && required == false {
+				r, err := s.requiredUnlessCheck(val, elName, cond)
+				if err != nil {
+					return err
+				}
+				required = r
+			}
+
+			if required == true && s.optIsUsed(elName) == false && s.requiredDefaultCheck(tag) == false {
+				return fmt.Errorf("required option '%s' is not specified", elName)
+			}
+
+			// Empty strings (including values of empty ENV variables) don't satisfy the requirement
+			if required == true && s.optIsUsed(elName) == true && vf.Kind() == reflect.String && vf.Len() == 0 {
+				return fmt.Errorf("required option '%s' is empty", elName)
+			}
+
+			// Sub-options of pointer options are checked only if the option is specified in config file,
+			// so absent blocks allocated by `alloc_defaults` are skipped the same way as nil ones
+			if vf.Kind() == reflect.Ptr && s.fieldSquashCheck(tf) == false && s.optIsUsed(elName) == false {
+				continue
+			}
+
+			if err := s.checkUsedRequredOpts(vf, elName, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			vf := val.Index(i)
+
+			elName := fmt.Sprintf("%s[%d]", parentName, i)
+
+			if err := s.checkUsedRequredOpts(vf, elName, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range val.MapKeys() {
+			vf := val.MapIndex(k)
+
+			elName := fmt.Sprintf("%s[%v]", parentName, k)
+
+			if err := s.checkUsedRequredOpts(vf, elName, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// requiredUnlessCheck checks option `elName` of struct `val` is required in accordance with condition `cond`
+// in format `sibling value`, i.e. the option is required unless sibling option has the specified value
+func (s *Settings) requiredUnlessCheck(val reflect.Value, elName string, cond string) (bool, error) {
+
+	p := strings.SplitN(strings.TrimSpace(cond), " ", 2)
+	if len(p) != 2 {
+		return false, fmt.Errorf("option '%s' `%s` must be in format `sibling value`", elName, tagConfRequiredUnlessName)
+	}
+
+	v, ok := s.fieldByConfName(val, p[0])
+	if ok == false {
+		return false, fmt.Errorf("sibling option '%s' for option '%s' is not found", p[0], elName)
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() == true {
+			return true, nil
+		}
+		v = v.Elem()
+	}
+
+	return fmt.Sprint(v.Interface()) != strings.TrimSpace(p[1]), nil
+}
+
+// requiredDefaultCheck checks required option with extra options `tag` is satisfied by its default value
+func (s *Settings) requiredDefaultCheck(tag string) bool {
+
+	if s.RequiredDefaultDeny == true {
+		return false
+	}
+
+	return s.tagKeyCheck(tag, tagConfDefaultName) == true || s.tagKeyCheck(tag, tagConfDefaultBuildName) == true
+}
+
+// checkRawRequredOpts checks that raw config data contains all requirement options
+func (s *Settings) checkRawRequredOpts(raw interface{}, t reflect.Type, parentName string) error {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	rv := reflect.ValueOf(raw)
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if rv.Kind() != reflect.Map {
+			return nil
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			tf := t.Field(i)
+
+			elName := s.optNameJoin(parentName, tf)
+
+			if s.fieldSquashCheck(tf) == true {
+				if err := s.checkRawRequredOpts(raw, tf.Type, elName); err != nil {
+					return err
+				}
+				continue
+			}
+
+			k, ok := s.rawMapKey(rv, s.fieldNameNormalize(tf))
+			if ok == false {
+				tag := tf.Tag.Get(tagConfExtraOptsName)
+				if s.tagKeyCheck(tag, tagConfRequiredName) == true && s.requiredDefaultCheck(tag) == false {
+					return fmt.Errorf("required option '%s' is not specified", elName)
+				}
+				// Absent non-pointer nested struct is checked as an empty block, since its required sub-options can't be set
+				if tf.Type.Kind() == reflect.Struct && s.textUnmarshalerCheck(tf.Type) == false {
+					if err := s.checkRawRequredOpts(make(map[string]interface{}), tf.Type, elName); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			if v, ok := rv.MapIndex(k).Interface().(string); ok == true && v == "" && tf.Type.Kind() == reflect.String && s.tagKeyCheck(tf.Tag.Get(tagConfExtraOptsName), tagConfRequiredName) == true {
+				return fmt.Errorf("required option '%s' is empty", elName)
+			}
+
+			if err := s.checkRawRequredOpts(rv.MapIndex(k).Interface(), tf.Type, elName); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() != reflect.Slice {
+			return nil
+		}
+
+		for i := 0; i < rv.Len(); i++ {
+
+			elName := fmt.Sprintf("%s[%d]", parentName, i)
+
+			if err := s.checkRawRequredOpts(rv.Index(i).Interface(), t.Elem(), elName); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if rv.Kind() != reflect.Map {
+			return nil
+		}
+
+		for _, k := range rv.MapKeys() {
+
+			elName := fmt.Sprintf("%s[%v]", parentName, k)
+
+			if err := s.checkRawRequredOpts(rv.MapIndex(k).Interface(), t.Elem(), elName); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sanitizeStrings recursively sanitizes all string values within `val` (see `SanitizeStrings` settings field)
+func (s *Settings) sanitizeStrings(val reflect.Value) {
+
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() == false {
+			s.sanitizeStrings(val.Elem())
+		}
+	case reflect.String:
+		if val.CanSet() == true {
+			val.SetString(s.stringSanitize(val.String()))
+		}
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			s.sanitizeStrings(val.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			s.sanitizeStrings(val.Index(i))
+		}
+	case reflect.Map:
+		for _, k := range val.MapKeys() {
+
+			// Create copy of element to make it writable
+			t := reflect.New(val.Type().Elem()).Elem()
+			t.Set(val.MapIndex(k))
+
+			s.sanitizeStrings(t)
+
+			val.SetMapIndex(k, t)
+		}
+	}
+}
+
+// stringSanitize strips zero-width characters from `str`, replaces non-breaking spaces with regular ones
+// and trims leading and trailing whitespaces
+func (s *Settings) stringSanitize(str string) string {
+
+	str = strings.Map(func(r rune) rune {
+		switch r {
+		case '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff':
+			return -1
+		case '\u00a0', '\u202f':
+			return ' '
+		}
+		return r
+	}, str)
+
+	return strings.TrimFunc(str, unicode.IsSpace)
+}
+
+// decryptOpts decrypts values of string options marked as encrypted and specified in config file
+func (s *Settings) decryptOpts(val reflect.Value) error {
+
+	return s.walkFields(val, "", func(vf reflect.Value, tf reflect.StructField, elName string) error {
+
+		if s.tagKeyCheck(tf.Tag.Get(tagConfExtraOptsName), tagConfEncryptedName) == false {
+			return nil
+		}
+
+		if vf.Kind() != reflect.String {
+			return fmt.Errorf("encrypted option '%s' must be a string", elName)
+		}
+
+		if s.optIsUsed(elName) == false {
+			return nil
+		}
+
+		if s.Decryptor == nil {
+			return fmt.Errorf("decryptor is not specified for encrypted option '%s'", elName)
+		}
+
+		d, err := s.Decryptor([]byte(vf.String()))
+		if err != nil {
+			return fmt.Errorf("option '%s' decrypt error: %v", elName, err)
+		}
+
+		vf.SetString(string(d))
+
+		return nil
+	})
+}
+
+// validateOpts checks options values in accordance with validation extra options
+func (s *Settings) validateOpts(val reflect.Value) error {
+
+	return s.walkFields(val, "", func(vf reflect.Value, tf reflect.StructField, elName string) error {
+
+		tag := tf.Tag.Get(tagConfExtraOptsName)
+
+		if s.tagKeyCheck(tag, tagConfNotEmptyName) == true && s.valueIsEmpty(vf) == true {
+			return fmt.Errorf("option '%s' must not be empty", elName)
+		}
+
+		if by, ok := s.tagValGet(tag, tagConfSortedByName); ok == true {
+			if err := s.checkSorted(vf, elName, by); err != nil {
+				return err
+			}
+		}
+
+		if err := s.checkBounds(vf, elName, tag); err != nil {
+			return err
+		}
+
+		if err := s.checkLen(vf, elName, tag); err != nil {
+			return err
+		}
+
+		if p, ok := s.tagValGet(tag, tagConfRegexpName); ok == true {
+			if err := s.checkRegexp(vf, elName, []string{p}); err != nil {
+				return err
+			}
+		}
+
+		if p, ok := s.tagValGet(tag, tagConfRegexpAnyName); ok == true {
+			if err := s.checkRegexp(vf, elName, s.regexpAlternativesSplit(p)); err != nil {
+				return err
+			}
+		}
+
+		if schemes, ok := s.tagValGet(tag, tagConfSchemesName); ok == true {
+			if err := s.checkSchemes(vf, elName, schemes); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// optGroup contains options of a group (see `group` extra option) within a struct
+type optGroup struct {
+	atLeast int
+	opts    []string
+	set     []string
+}
+
+// checkGroups checks that at least `at_least` options of each group are specified in config file.
+// Groups are formed by options of the same struct with equal `group` extra option values
+func (s *Settings) checkGroups(val reflect.Value) error {
+
+	groups := make(map[string]*optGroup)
+
+	err := s.walkFields(val, "", func(vf reflect.Value, tf reflect.StructField, elName string) error {
+
+		tag := tf.Tag.Get(tagConfExtraOptsName)
+
+		name, ok := s.tagValGet(tag, tagConfGroupName)
+		if ok == false {
+			return nil
+		}
+
+		// Group is identified by parent option path and group name
+		opt := s.fieldNameNormalize(tf)
+		key := fmt.Sprintf("%s/%s", strings.TrimSuffix(strings.TrimSuffix(elName, opt), "."), name)
+
+		g, ok := groups[key]
+		if ok == false {
+			g = &optGroup{}
+			groups[key] = g
+		}
+
+		g.opts = append(g.opts, elName)
+		if s.optIsUsed(elName) == true {
+			g.set = append(g.set, elName)
+		}
+
+		if str, ok := s.tagValGet(tag, tagConfAtLeastName); ok == true {
+			n, err := strconv.Atoi(str)
+			if err != nil {
+				return fmt.Errorf("option '%s' `%s` value error: %v", elName, tagConfAtLeastName, err)
+			}
+			if n > g.atLeast {
+				g.atLeast = n
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		g := groups[k]
+		if len(g.set) < g.atLeast {
+			return fmt.Errorf("at least %d of options '%s' must be set, %d set", g.atLeast, strings.Join(g.opts, "', '"), len(g.set))
+		}
+	}
+
+	return nil
+}
+
+// checkBounds checks numeric option value is within `min` and `max` bounds specified in extra options `tag`.
+// With `clamp` extra option values out of bounds are replaced with the nearest bound instead of an error.
+// Default values out of bounds are always an error
+func (s *Settings) checkBounds(val reflect.Value, elName string, tag string) error {
+
+	// Options neither specified in config file nor having default values are not checked
+	if s.optIsUsed(elName) == false && s.tagKeyCheck(tag, tagConfDefaultName) == false {
+		return nil
+	}
+
+	for _, b := range []string{tagConfMinName, tagConfMaxName} {
+
+		str, ok := s.tagValGet(tag, b)
+		if ok == false {
+			continue
+		}
+
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return fmt.Errorf("option '%s' with `%s` must be a number or a duration", elName, b)
+		}
+
+		bound, err := s.convValue(str, val.Type())
+		if err != nil {
+			return fmt.Errorf("option '%s' `%s` value error: %v", elName, b, err)
+		}
+
+		var out bool
+		if b == tagConfMinName {
+			out, err = s.valueLess(val, bound)
+		} else {
+			out, err = s.valueLess(bound, val)
+		}
+		if err != nil {
+			return err
+		}
+
+		if out == false {
+			continue
+		}
+
+		if s.optIsUsed(elName) == false {
+			return fmt.Errorf("option '%s' default value %v is out of bounds (%s %s)", elName, val.Interface(), b, str)
+		}
+
+		if s.tagKeyCheck(tag, tagConfClampName) == false {
+			return fmt.Errorf("option '%s' value %v is out of bounds (%s %s)", elName, val.Interface(), b, str)
+		}
+
+		val.Set(bound)
+	}
+
+	return nil
+}
+
+// checkLen checks length of string, slice or map option is within `minlen` and `maxlen` bounds
+// specified in extra options `tag`. Length of strings is a number of characters
+func (s *Settings) checkLen(val reflect.Value, elName string, tag string) error {
+
+	for _, b := range []string{tagConfMinLenName, tagConfMaxLenName} {
+
+		str, ok := s.tagValGet(tag, b)
+		if ok == false {
+			continue
+		}
+
+		n, err := strconv.Atoi(str)
+		if err != nil || n < 0 {
+			return fmt.Errorf("option '%s' `%s` value '%s' must be a non-negative integer", elName, b, str)
+		}
+
+		var l int
+
+		switch val.Kind() {
+		case reflect.String:
+			l = utf8.RuneCountInString(val.String())
+		case reflect.Slice, reflect.Array, reflect.Map:
+			l = val.Len()
+		default:
+			return fmt.Errorf("option '%s' with `%s` must be a string, slice or map", elName, b)
+		}
+
+		if b == tagConfMinLenName && l < n {
+			return fmt.Errorf("option '%s' length %d is less than minimum %d", elName, l, n)
+		}
+
+		if b == tagConfMaxLenName && l > n {
+			return fmt.Errorf("option '%s' length %d is greater than maximum %d", elName, l, n)
+		}
+	}
+
+	return nil
+}
+
+// checkRegexp checks string option value matches at least one of `patterns`
+func (s *Settings) checkRegexp(val reflect.Value, elName string, patterns []string) error {
+
+	if val.Kind() != reflect.String {
+		return fmt.Errorf("option '%s' with regexp check must be a string", elName)
+	}
+
+	for _, p := range patterns {
+
+		r, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("option '%s' regexp error: %v", elName, err)
+		}
+
+		if r.MatchString(val.String()) == true {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("option '%s' value '%s' doesn't match pattern '%s'", elName, val.String(), strings.Join(patterns, "|"))
+}
+
+// regexpAlternativesSplit splits `str` into patterns separated by pipes.
+// Escaped pipes and pipes within groups or character classes are kept within patterns
+func (s *Settings) regexpAlternativesSplit(str string) []string {
+
+	var (
+		patterns []string
+		depth    int
+		class    bool
+		start    int
+	)
+
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; {
+		case c == '\\':
+			i++
+		case class == true:
+			if c == ']' {
+				class = false
+			}
+		case c == '[':
+			class = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			patterns = append(patterns, str[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(patterns, str[start:])
+}
+
+// callValidators calls `Validate` for nested options and the root `val` implementing `Validator` interface
+func (s *Settings) callValidators(val reflect.Value) error {
+
+	if err := s.walkFields(val, "", func(vf reflect.Value, tf reflect.StructField, elName string) error {
+
+		if err := s.validatorCall(vf); err != nil {
+			return fmt.Errorf("option '%s': %v", elName, err)
+		}
+
+		switch vf.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < vf.Len(); i++ {
+				if err := s.validatorCall(vf.Index(i)); err != nil {
+					return fmt.Errorf("option '%s[%d]': %v", elName, i, err)
+				}
+			}
+		case reflect.Map:
+			for _, k := range vf.MapKeys() {
+
+				// Create copy of element to make it addressable
+				t := reflect.New(vf.Type().Elem()).Elem()
+				t.Set(vf.MapIndex(k))
+
+				if err := s.validatorCall(t); err != nil {
+					return fmt.Errorf("option '%s[%v]': %v", elName, k, err)
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return s.validatorCall(val)
+}
+
+// validatorCall calls `Validate` if `val` implements `Validator` interface
+func (s *Settings) validatorCall(val reflect.Value) error {
+
+	if (val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface) && val.IsNil() == true {
+		return nil
+	}
+
+	if val.CanAddr() == true && val.Addr().CanInterface() == true {
+		if v, ok := val.Addr().Interface().(Validator); ok == true {
+			return v.Validate()
+		}
+	}
+
+	if val.CanInterface() == true {
+		if v, ok := val.Interface().(Validator); ok == true {
+			return v.Validate()
+		}
+	}
+
+	return nil
+}
+
+// valueIsEmpty checks that string `val` is empty, slice or map `val` has no elements or pointer `val` is nil
+func (s *Settings) valueIsEmpty(val reflect.Value) bool {
+
+	switch val.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return val.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return val.IsNil()
+	}
+
+	return false
+}
+
+// checkSorted checks that values of sub-option `by` of slice `val` elements are non-decreasing
+func (s *Settings) checkSorted(val reflect.Value, elName string, by string) error {
+
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return fmt.Errorf("option '%s' with `%s` must be a slice", elName, tagConfSortedByName)
+	}
+
+	var prev reflect.Value
+
+	for i := 0; i < val.Len(); i++ {
+
+		v, ok := s.fieldByConfName(val.Index(i), by)
+		if ok == false {
+			return fmt.Errorf("option '%s[%d]' has no sub-option '%s'", elName, i, by)
+		}
+
+		if i > 0 {
+			less, err := s.valueLess(v, prev)
+			if err != nil {
+				return fmt.Errorf("option '%s[%d].%s': %v", elName, i, by, err)
+			}
+			if less == true {
+				return fmt.Errorf("option '%s' is not sorted by '%s' at index %d", elName, by, i)
+			}
+		}
+
+		prev = v
+	}
+
+	return nil
+}
+
+// fieldByConfName returns field of struct `val` with option name `name`
+func (s *Settings) fieldByConfName(val reflect.Value, name string) (reflect.Value, bool) {
+
+	val = reflect.Indirect(val)
+	if val.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	for i := 0; i < val.NumField(); i++ {
+		if s.fieldNameNormalize(val.Type().Field(i)) == name {
+			return val.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// valueByPath returns value of struct `val` field with dotted option path `path`
+func (s *Settings) valueByPath(val reflect.Value, path string) (reflect.Value, bool) {
+
+	for _, name := range s.optPathSplit(path) {
+
+		if val.Kind() == reflect.Ptr && val.IsNil() == true {
+			return reflect.Value{}, false
+		}
+
+		v, ok := s.fieldByConfName(val, name)
+		if ok == false {
+			return reflect.Value{}, false
+		}
+
+		val = v
+	}
+
+	return val, true
+}
+
+// valueLess reports whether scalar value `a` is less than `b`
+func (s *Settings) valueLess(a, b reflect.Value) (bool, error) {
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float(), nil
+	case reflect.String:
+		return a.String() < b.String(), nil
+	}
+
+	return false, fmt.Errorf("values of type `%s` are not comparable", a.Type())
+}
+
+// walkFields recursively walks through `val` and calls `fn` for every struct field.
+// Map elements are passed to `fn` as writable copies which are set back into the map after walk.
+func (s *Settings) walkFields(val reflect.Value, parentName string, fn func(vf reflect.Value, tf reflect.StructField, elName string) error) error {
+
+	if val.Kind() == reflect.Ptr && val.IsNil() == true {
+		return nil
+	}
+
+	// Check val is pointer
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+
+	switch val.Type().Kind() {
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			vf := val.Field(i)
+			tf := val.Type().Field(i)
+
+			elName := s.optNameJoin(parentName, tf)
+
+			if err := fn(vf, tf, elName); err != nil {
+				return err
+			}
+
+			if err := s.walkFields(vf, elName, fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			vf := val.Index(i)
+
+			elName := fmt.Sprintf("%s[%d]", parentName, i)
+
+			if err := s.walkFields(vf, elName, fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range val.MapKeys() {
+			vf := val.MapIndex(k)
+
+			// Create copy of element to make it writable
+			t := reflect.Indirect(reflect.New(vf.Type()))
+			t.Set(vf)
+
+			elName := fmt.Sprintf("%s[%v]", parentName, k)
+
+			if err := s.walkFields(t, elName, fn); err != nil {
+				return err
+			}
+
+			val.SetMapIndex(k, t)
+		}
+	}
+
+	return nil
+}
+
+// rawSubtreeGet returns subtree of raw config data with dotted path `path`
+func (s *Settings) rawSubtreeGet(rawConf map[string]interface{}, path string) (map[string]interface{}, error) {
+
+	var raw interface{} = rawConf
+
+	for _, k := range s.optPathSplit(path) {
+
+		rv := reflect.ValueOf(raw)
+		if rv.Kind() != reflect.Map {
+			return nil, fmt.Errorf("root key '%s' is not a map", path)
+		}
+
+		v := rv.MapIndex(reflect.ValueOf(k))
+		if v.IsValid() == false {
+			if s.RootKeyOptional == true {
+				return make(map[string]interface{}), nil
+			}
+			return nil, fmt.Errorf("root key '%s' is not found", path)
+		}
+
+		raw = v.Interface()
+	}
+
+	rv := reflect.ValueOf(raw)
+	if rv.Kind() != reflect.Map {
+		return nil, fmt.Errorf("root key '%s' is not a map", path)
+	}
+
+	// Nested YAML maps have interface keys
+	m := make(map[string]interface{})
+	for _, k := range rv.MapKeys() {
+		m[fmt.Sprint(k.Interface())] = rv.MapIndex(k).Interface()
+	}
+
+	return m, nil
+}
+
+// checkEnvRefs checks all ENV variables referenced in raw config are set
+func (s *Settings) checkEnvRefs(raw interface{}) error {
+
+	missing := make(map[string]bool)
+
+	s.envRefsCollect(raw, regexp.MustCompile(regexpEnv), missing)
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var names []string
+	for n := range missing {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return fmt.Errorf("empty ENV variables: %s", strings.Join(names, ", "))
+}
+
+// envRefsCollect puts names of empty ENV variables referenced in raw value `raw` into `missing`
+func (s *Settings) envRefsCollect(raw interface{}, r *regexp.Regexp, missing map[string]bool) {
+
+	rv := reflect.ValueOf(raw)
+
+	switch rv.Kind() {
+	case reflect.Map:
+		for _, k := range rv.MapKeys() {
+			s.envRefsCollect(rv.MapIndex(k).Interface(), r, missing)
+		}
+	case reflect.Slice:
+		for i := 0; i < rv.Len(); i++ {
+			s.envRefsCollect(rv.Index(i).Interface(), r, missing)
+		}
+	case reflect.String:
+		if m := r.FindStringSubmatch(rv.String()); m != nil && os.Getenv(s.envName(m[1])) == "" {
+			missing[s.envName(m[1])] = true
+		}
+	}
+}
+
+// maxDepth returns maximum nesting depth of config (see `MaxDepth` settings field)
+func (s *Settings) maxDepth() int {
+
+	if s.MaxDepth <= 0 {
+		return maxDepthDefault
+	}
+
+	return s.MaxDepth
+}
+
+// depthCheck checks that nesting depth `depth` of struct, slice or map option `val` with path `name` does not exceed `MaxDepth`
+func (s *Settings) depthCheck(val reflect.Value, name string, depth int) error {
+
+	switch val.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		if depth > s.maxDepth() {
+			return fmt.Errorf("option '%s' exceeds maximum nesting depth %d", name, s.maxDepth())
+		}
+	}
+
+	return nil
+}
+
+// checkDepth checks that nesting depth of raw config data does not exceed `MaxDepth`
+func (s *Settings) checkDepth(raw interface{}, depth int) error {
+
+	rv := reflect.ValueOf(raw)
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if depth > s.maxDepth() {
+			return fmt.Errorf("config exceeds maximum nesting depth %d", s.maxDepth())
+		}
+		for _, k := range rv.MapKeys() {
+			if err := s.checkDepth(rv.MapIndex(k).Interface(), depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if depth > s.maxDepth() {
+			return fmt.Errorf("config exceeds maximum nesting depth %d", s.maxDepth())
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := s.checkDepth(rv.Index(i).Interface(), depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkStrictOpts checks that raw values of options marked as strict are decodable
+// into the options types with neither weak conversions nor conversions from strings.
+// Strings are converted to other types only if they are obtained from ENV variables.
+// Also unknown options are denied within strict subtrees regardless of `UnknownDeny` settings field.
+func (s *Settings) checkStrictOpts(rawConf map[string]interface{}, t reflect.Type) error {
+
+	return s.walkRaw(rawConf, t, "", func(m reflect.Value, k reflect.Value, tf reflect.StructField, elName string) error {
+
+		if s.tagKeyCheck(tf.Tag.Get(tagConfExtraOptsName), tagConfStrictName) == false {
+			return nil
+		}
+
+		// Only unknown options are denied within strict subtrees, sub-options are decoded as usual
+		if s.strictSubtreeCheck(tf.Type) == true {
+			return s.checkStrictSubtree(m.MapIndex(k).Interface(), tf.Type, elName)
+		}
+
+		var md mapstructure.Metadata
+
+		hooks := []mapstructure.DecodeHookFunc{s.decodeStrictRefs}
+		hooks = append(hooks, s.DecodeHooks...)
+		hooks = append(hooks, s.decodeBig, s.decodeScanner, s.decodeText, s.decodeDuration)
+
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			Metadata:   &md,
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(hooks...),
+			Result:     reflect.New(tf.Type).Interface(),
+			TagName:    tagConfName,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := decoder.Decode(m.MapIndex(k).Interface()); err != nil {
+			return fmt.Errorf("strict option '%s': %v", elName, err)
+		}
+
+		return nil
+	})
+}
+
+// strictSubtreeCheck checks option of type `t` with `strict` extra option is a subtree of options
+// (struct, or slice or map of structs) rather than a scalar option
+func (s *Settings) strictSubtreeCheck(t reflect.Type) bool {
+
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			if s.bytesCheck(t) == true {
+				return false
+			}
+			t = t.Elem()
+		case reflect.Struct:
+			return s.textUnmarshalerCheck(t) == false && s.scannerCheck(t) == false
+		default:
+			return false
+		}
+	}
+}
+
+// checkStrictSubtree checks raw value `raw` of strict subtree option of type `t` with path `parentName`
+// contains no unknown options
+func (s *Settings) checkStrictSubtree(raw interface{}, t reflect.Type, parentName string) error {
+
+	// Squashed structs are walked with the same raw maps as its parent structs, which are checked already
+	checked := make(map[uintptr]bool)
+
+	return s.walkRawStructs(raw, t, parentName, func(m reflect.Value, t reflect.Type, parentName string) error {
+
+		if checked[m.Pointer()] == true || s.textUnmarshalerCheck(t) == true {
+			return nil
+		}
+		checked[m.Pointer()] = true
+
+		// Unmatched options are collected into `remain` field
+		for i := 0; i < t.NumField(); i++ {
+			if s.fieldRemainCheck(t.Field(i)) == true {
+				return nil
+			}
+		}
+
+		known := make(map[string]bool)
+		s.structOptNames(t, known)
+
+		var unknown []string
+		for _, k := range m.MapKeys() {
+			if mk := fmt.Sprintf("%v", k.Interface()); known[strings.ToLower(mk)] == false {
+				unknown = append(unknown, mk)
+			}
+		}
+
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return fmt.Errorf("unknown option '%s'", s.optPathJoin(parentName, unknown[0]))
+		}
+
+		return nil
+	})
+}
+
+// prepareRawOpts prepares raw string values of options in accordance with its extra options
+// (e.g. normalizes numbers with comma decimal separator). ENV variables are substituted for such options
+func (s *Settings) prepareRawOpts(rawConf map[string]interface{}, t reflect.Type) error {
+
+	return s.walkRaw(rawConf, t, "", func(m reflect.Value, k reflect.Value, tf reflect.StructField, elName string) error {
+
+		tag := tf.Tag.Get(tagConfExtraOptsName)
+
+		// Bare numbers of durations are converted to strings to be normalized with unit
+		if tf.Type == durationType && s.tagKeyCheck(tag, tagConfUnitName) == true {
+			switch v := m.MapIndex(k).Interface().(type) {
+			case int, int64, uint64, float64:
+				m.SetMapIndex(k, reflect.ValueOf(s.optStrNormalize(fmt.Sprint(v), tf.Type, tag)))
+				return nil
+			}
+		}
+
+		// Scalar values of string options are formatted without weak conversions
+		if tf.Type.Kind() == reflect.String && s.tagKeyCheck(tag, tagConfStringifyName) == true {
+			if str, ok := s.scalarFormat(m.MapIndex(k).Interface()); ok == true {
+				m.SetMapIndex(k, reflect.ValueOf(str))
+				return nil
+			}
+		}
+
+		str, ok := m.MapIndex(k).Interface().(string)
+		if ok == false {
+			return nil
+		}
+
+		// ENV variables must be substituted before normalization.
+		// Substitution errors are reported by decoder
+		e, err := s.refResolve(str)
+		if err != nil {
+			return nil
+		}
+
+		// Comma-separated values are split into slice elements
+		if tf.Type.Kind() == reflect.Slice && s.tagKeyCheck(tag, tagConfCSVName) == true {
+			l := []interface{}{}
+			if strings.TrimSpace(e) != "" {
+				for _, v := range strings.Split(e, ",") {
+					l = append(l, strings.TrimSpace(v))
+				}
+			}
+			m.SetMapIndex(k, reflect.ValueOf(l))
+			return nil
+		}
+
+		// Byte slices are decoded from base64 (or other encoding specified by extra option)
+		if s.bytesCheck(tf.Type) == true {
+			b, err := s.bytesDecode(e, tag)
+			if err != nil {
+				return fmt.Errorf("option '%s' value error: %v", elName, err)
+			}
+			m.SetMapIndex(k, reflect.ValueOf(b))
+			return nil
+		}
+
+		n := s.optStrNormalize(e, tf.Type, tag)
+		if n == e {
+			return nil
+		}
+
+		m.SetMapIndex(k, reflect.ValueOf(n))
+
+		return nil
+	})
+}
+
+// scalarFormat formats non-string scalar value `v` as a string. Floats are formatted in the shortest
+// decimal representation without exponent, so integral floats have no fractional part (e.g. `1.0` is `1`)
+func (s *Settings) scalarFormat(v interface{}) (string, bool) {
+
+	switch e := v.(type) {
+	case bool:
+		return strconv.FormatBool(e), true
+	case int:
+		return strconv.Itoa(e), true
+	case int64:
+		return strconv.FormatInt(e, 10), true
+	case uint64:
+		return strconv.FormatUint(e, 10), true
+	case float64:
+		return strconv.FormatFloat(e, 'f', -1, 64), true
+	}
+
+	return "", false
+}
+
+// prepareWeakOpts replaces raw values of options listed in `WeaklyTypedFields` with values
+// decoded into the options types with weak conversions
+func (s *Settings) prepareWeakOpts(rawConf map[string]interface{}, t reflect.Type) error {
+
+	if len(s.WeaklyTypedFields) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]bool)
+	for _, f := range s.WeaklyTypedFields {
+		fields[f] = true
+	}
+
+	return s.walkRaw(rawConf, t, "", func(m reflect.Value, k reflect.Value, tf reflect.StructField, elName string) error {
+
+		if fields[s.optPathIndexesStrip(elName)] == false || m.MapIndex(k).Interface() == nil {
+			return nil
+		}
+
+		ft := tf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct {
+			// Options decoded with `UnmarshalText` need no weak conversions
+			if s.textUnmarshalerCheck(ft) == true {
+				return nil
+			}
+			return fmt.Errorf("option '%s' listed in weakly typed fields must not be a struct", elName)
+		}
+
+		hooks := []mapstructure.DecodeHookFunc{s.decodeRefs}
+		hooks = append(hooks, s.DecodeHooks...)
+		hooks = append(hooks, s.decodeBig, s.decodeScanner, s.decodeText, s.decodeFromString)
+
+		r := reflect.New(ft)
+
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			WeaklyTypedInput: true,
+			DecodeHook:       mapstructure.ComposeDecodeHookFunc(hooks...),
+			Result:           r.Interface(),
+			TagName:          tagConfName,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := decoder.Decode(m.MapIndex(k).Interface()); err != nil {
+			return fmt.Errorf("option '%s': %v", elName, err)
+		}
+
+		m.SetMapIndex(k, r.Elem())
+
+		return nil
+	})
+}
+
+// bytesCheck checks type `t` is a byte slice decodable from encoded string
+func (s *Settings) bytesCheck(t reflect.Type) bool {
+
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+
+	// Types like `net.IP` are decoded with `UnmarshalText`
+	return s.textUnmarshalerCheck(t) == false
+}
+
+// bytesDecode decodes string `str` in encoding specified by extra options `tag` (base64 by default)
+func (s *Settings) bytesDecode(str string, tag string) ([]byte, error) {
+
+	e, _ := s.tagValGet(tag, tagConfEncodingName)
+
+	switch e {
+	case "", "base64":
+		return base64.StdEncoding.DecodeString(str)
+	case "hex":
+		return hex.DecodeString(str)
+	}
+
+	return nil, fmt.Errorf("unknown encoding '%s'", e)
+}
+
+// envOverlay sets options of struct type `t` (and its nested structs) with `env` extra option
+// within raw map `m` to values of specified ENV variables if they are set. Returns true if `m` is changed.
+// `visited` contains struct types being processed to stop on recursive types
+func (s *Settings) envOverlay(m reflect.Value, t reflect.Type, parentName string, visited map[reflect.Type]bool) bool {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || s.textUnmarshalerCheck(t) == true || visited[t] == true {
+		return false
+	}
+
+	visited[t] = true
+	defer delete(visited, t)
+
+	changed := false
+
+	for i := 0; i < t.NumField(); i++ {
+		tf := t.Field(i)
+
+		// Squashed struct options are contained in the same raw map
+		if s.fieldSquashCheck(tf) == true {
+			changed = s.envOverlay(m, tf.Type, parentName, visited) || changed
+			continue
+		}
+
+		name := s.fieldNameNormalize(tf)
+		elName := s.optNameJoin(parentName, tf)
+
+		k, ok := s.rawMapKey(m, name)
+		if ok == false {
+			k = reflect.ValueOf(name)
+		}
+
+		if env, ok := s.tagValGet(tf.Tag.Get(tagConfExtraOptsName), tagConfEnvName); ok == true {
+			if v, ok := os.LookupEnv(env); ok == true {
+				m.SetMapIndex(k, reflect.ValueOf(v))
+				s.sources[elName] = SourceEnv
+				changed = true
+				continue
+			}
+		}
+
+		// Nested struct options. Raw map is created if it's absent
+		sub := reflect.ValueOf(make(map[string]interface{}))
+		if e := m.MapIndex(k); e.IsValid() == true {
+			if e.Elem().Kind() != reflect.Map {
+				continue
+			}
+			sub = e.Elem()
+		}
+
+		if s.envOverlay(sub, tf.Type, elName, visited) == true {
+			m.SetMapIndex(k, sub)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// resolveAliases moves values of options specified by aliases or deprecated names within raw map `m`
+// of struct type `t` to actual options names. Actual names take precedence if both are specified
+func (s *Settings) resolveAliases(m reflect.Value, t reflect.Type, parentName string) error {
+
+	for i := 0; i < t.NumField(); i++ {
+		tf := t.Field(i)
+
+		// Aliases are moved silently, the first specified alias takes precedence
+		if aliases, ok := s.tagValGet(tf.Tag.Get(tagConfExtraOptsName), tagConfAliasesName); ok == true {
+			for _, a := range strings.Fields(aliases) {
+				s.rawKeyMove(m, a, s.fieldNameNormalize(tf))
+			}
+		}
+
+		old, ok := s.tagValGet(tf.Tag.Get(tagConfExtraOptsName), tagConfDeprecatedAliasName)
+		if ok == false {
+			continue
+		}
+
+		if s.rawKeyMove(m, old, s.fieldNameNormalize(tf)) == true {
+
+			oldName := s.optPathJoin(parentName, old)
+			elName := s.optNameJoin(parentName, tf)
+
+			s.warnings = append(s.warnings, fmt.Sprintf("option '%s' is deprecated, use '%s' instead", oldName, elName))
+
+			if s.OnDeprecated != nil {
+				s.OnDeprecated(oldName, elName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveRemain moves values of options within raw map `m` that have no matching fields of struct `t`
+// into the map under the key of field marked as `remain` (`conf:",remain"`)
+func (s *Settings) resolveRemain(m reflect.Value, t reflect.Type, parentName string) error {
+
+	var rf *reflect.StructField
+
+	for i := 0; i < t.NumField(); i++ {
+		if tf := t.Field(i); s.fieldRemainCheck(tf) == true {
+			rf = &tf
+			break
+		}
+	}
+
+	if rf == nil {
+		return nil
+	}
+
+	if rf.Type.Kind() != reflect.Map || rf.Type.Key().Kind() != reflect.String {
+		return fmt.Errorf("option '%s': remain field must be a map with string keys", s.optNameJoin(parentName, *rf))
+	}
+
+	known := make(map[string]bool)
+	s.structOptNames(t, known)
+
+	remain := make(map[string]interface{})
+
+	for _, k := range m.MapKeys() {
+
+		mk := fmt.Sprintf("%v", k.Interface())
+		if known[strings.ToLower(mk)] == true {
+			continue
+		}
+
+		remain[mk] = m.MapIndex(k).Interface()
+		m.SetMapIndex(k, reflect.Value{})
+	}
+
+	m.SetMapIndex(reflect.ValueOf(s.fieldNameNormalize(*rf)), reflect.ValueOf(remain))
+
+	return nil
+}
+
+// structOptNames adds lowercased names of options of struct `t` (including squashed structs options)
+// into `names`. Field marked as `remain` is skipped
+func (s *Settings) structOptNames(t reflect.Type, names map[string]bool) {
+
+	for i := 0; i < t.NumField(); i++ {
+		tf := t.Field(i)
+
+		if s.fieldRemainCheck(tf) == true {
+			continue
+		}
+
+		if s.fieldSquashCheck(tf) == true {
+			ft := tf.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.structOptNames(ft, names)
+			}
+			continue
+		}
+
+		names[strings.ToLower(s.fieldNameNormalize(tf))] = true
+	}
+}
+
+// rawKeyMove moves value with key `from` to key `to` within raw map `m` if key `to` is not present.
+// Returns true if key `from` was present
+func (s *Settings) rawKeyMove(m reflect.Value, from, to string) bool {
+
+	kf, ok := s.rawMapKey(m, from)
+	if ok == false {
+		return false
+	}
+
+	if _, ok := s.rawMapKey(m, to); ok == false {
+		m.SetMapIndex(reflect.ValueOf(to), m.MapIndex(kf))
+	}
+
+	m.SetMapIndex(kf, reflect.Value{})
+
+	return true
+}
+
+// walkRawStructs recursively walks through raw config data in accordance with type `t`
+// and calls `fn` for every raw map corresponding to a struct
+func (s *Settings) walkRawStructs(raw interface{}, t reflect.Type, parentName string, fn func(m reflect.Value, t reflect.Type, parentName string) error) error {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Struct && reflect.ValueOf(raw).Kind() == reflect.Map {
+		if err := fn(reflect.ValueOf(raw), t, parentName); err != nil {
+			return err
+		}
+	}
+
+	if t.Kind() == reflect.Struct || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		return s.walkRawLevel(raw, t, parentName, func(raw interface{}, t reflect.Type, elName string) error {
+			return s.walkRawStructs(raw, t, elName, fn)
+		})
+	}
+
+	return nil
+}
+
+// walkRawLevel calls `fn` for every element of raw config data `raw` (struct fields, slice elements or map values)
+// in accordance with type `t`
+func (s *Settings) walkRawLevel(raw interface{}, t reflect.Type, parentName string, fn func(raw interface{}, t reflect.Type, elName string) error) error {
+
+	rv := reflect.ValueOf(raw)
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if rv.Kind() != reflect.Map {
+			return nil
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			tf := t.Field(i)
+
+			// Squashed struct options are contained in the same raw map
+			if s.fieldSquashCheck(tf) == true {
+				if err := fn(raw, tf.Type, parentName); err != nil {
+					return err
+				}
+				continue
+			}
+
+			k, ok := s.rawMapKey(rv, s.fieldNameNormalize(tf))
+			if ok == false {
+				continue
+			}
+
+			if err := fn(rv.MapIndex(k).Interface(), tf.Type, s.optNameJoin(parentName, tf)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() != reflect.Slice {
+			return nil
+		}
+
+		for i := 0; i < rv.Len(); i++ {
+			if err := fn(rv.Index(i).Interface(), t.Elem(), fmt.Sprintf("%s[%d]", parentName, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if rv.Kind() != reflect.Map {
+			return nil
+		}
+
+		for _, k := range rv.MapKeys() {
+			if err := fn(rv.MapIndex(k).Interface(), t.Elem(), fmt.Sprintf("%s[%v]", parentName, k)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// walkRaw recursively walks through raw config data in accordance with type `t`
+// and calls `fn` for every struct field found in the data.
+// Arguments `m` and `k` for `fn` are the raw map containing field value and the key of value within it.
+func (s *Settings) walkRaw(raw interface{}, t reflect.Type, parentName string, fn func(m reflect.Value, k reflect.Value, tf reflect.StructField, elName string) error) error {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	rv := reflect.ValueOf(raw)
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if rv.Kind() != reflect.Map {
+			return nil
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			tf := t.Field(i)
+
+			elName := s.optNameJoin(parentName, tf)
+
+			// Squashed struct options are contained in the same raw map
+			if s.fieldSquashCheck(tf) == true {
+				if err := s.walkRaw(raw, tf.Type, elName, fn); err != nil {
+					return err
+				}
+				continue
+			}
+
+			k, ok := s.rawMapKey(rv, s.fieldNameNormalize(tf))
+			if ok == false {
+				continue
+			}
+
+			if err := fn(rv, k, tf, elName); err != nil {
+				return err
+			}
+
+			if err := s.walkRaw(rv.MapIndex(k).Interface(), tf.Type, elName, fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() != reflect.Slice {
+			return nil
+		}
+
+		for i := 0; i < rv.Len(); i++ {
+
+			elName := fmt.Sprintf("%s[%d]", parentName, i)
+
+			if err := s.walkRaw(rv.Index(i).Interface(), t.Elem(), elName, fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if rv.Kind() != reflect.Map {
+			return nil
+		}
+
+		for _, k := range rv.MapKeys() {
+
+			elName := fmt.Sprintf("%s[%v]", parentName, k)
+
+			if err := s.walkRaw(rv.MapIndex(k).Interface(), t.Elem(), elName, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// rawMapKey looks for the key `name` in raw map `m` the same way as mapstructure does
+// (exact match first, case-insensitive match otherwise)
+func (s *Settings) rawMapKey(m reflect.Value, name string) (reflect.Value, bool) {
+
+	k := reflect.ValueOf(name)
+	if m.MapIndex(k).IsValid() == true {
+		return k, true
+	}
+
+	for _, k := range m.MapKeys() {
+		if mk, ok := k.Interface().(string); ok == true && strings.EqualFold(mk, name) == true {
+			return k, true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+func (s *Settings) checkUnknownOpts() error {
+	if s.UnknownDeny == true && len(s.md.Unused) > 0 {
+		return fmt.Errorf("unknown option '%s'", s.md.Unused[0])
+	}
+	return nil
+}
+
+// decodeRefs substitutes references to ENV variables and secrets with its values.
+func (s *Settings) decodeRefs(f reflect.Type, t reflect.Type, v interface{}) (interface{}, error) {
+
+	if f.Kind() != reflect.String {
+		return v, nil
+	}
+
+	str, err := s.refResolve(v.(string))
+	if err != nil {
+		return v, err
+	}
+
+	return str, nil
+}
+
+// decodeStrictRefs decodes values for strict options. Only values of ENV variables and secrets are converted from strings to other types.
+func (s *Settings) decodeStrictRefs(f reflect.Type, t reflect.Type, v interface{}) (interface{}, error) {
+
+	if f.Kind() != reflect.String {
+		return v, nil
+	}
+
+	str, err := s.refResolve(v.(string))
+	if err != nil {
+		return v, err
+	}
+
+	if str == v.(string) {
+		return v, nil
+	}
+
+	return s.convFromString(str, t)
+}
+
+// decodeDuration decodes duration strings (e.g. `30s`) into `time.Duration` options. Duration strings are the canonical
+// form of such options, so they are decoded for strict options too
+func (s *Settings) decodeDuration(f reflect.Type, t reflect.Type, v interface{}) (interface{}, error) {
+
+	if f.Kind() != reflect.String || t != durationType {
+		return v, nil
+	}
+
+	return s.convFromString(v.(string), t)
+}
+
+// refResolve returns value of ENV variable or secret if `str` is a reference to it, otherwise returns `str` as is
+func (s *Settings) refResolve(str string) (string, error) {
+
+	str, err := s.envResolve(str)
+	if err != nil {
+		return str, err
+	}
+
+	return s.secretResolve(str)
+}
+
+// secretResolve returns secret value obtained by `SecretResolver` if `str` has format `<SecretPrefix>REFERENCE`,
+// otherwise returns `str` as is
+func (s *Settings) secretResolve(str string) (string, error) {
+
+	if s.SecretResolver == nil {
+		return str, nil
+	}
+
+	prefix := s.SecretPrefix
+	if prefix == "" {
+		prefix = secretPrefixDefault
+	}
+
+	if strings.HasPrefix(str, prefix) == false {
+		return str, nil
+	}
+
+	ref := strings.TrimPrefix(str, prefix)
+
+	v, err := s.SecretResolver(ref)
+	if err != nil {
+		return str, fmt.Errorf("secret '%s' resolve error: %v", ref, err)
+	}
+
+	return v, nil
+}
+
+// envResolve returns value of ENV variable if `str` has format `ENV:VARIABLE_NAME`, otherwise returns `str` as is
+func (s *Settings) envResolve(str string) (string, error) {
+
+	if s.DisableEnv == true {
+		return str, nil
+	}
+
+	var r = regexp.MustCompile(regexpEnv)
+
+	result := r.FindStringSubmatch(str)
+	if result == nil {
+		return str, nil
+	}
+
+	name := s.envName(result[1])
+
+	e := os.Getenv(name)
+	if e == "" {
+		return str, fmt.Errorf("empty ENV variable '%s'", name)
+	}
+
+	return e, nil
+}
+
+// envName returns name of ENV variable referenced as `ref`. `EnvVarPrefix` is prepended
+// unless `ref` starts with `!` marker
+func (s *Settings) envName(ref string) string {
+
+	if strings.HasPrefix(ref, envNoPrefixMarker) == true {
+		return strings.TrimPrefix(ref, envNoPrefixMarker)
+	}
+
+	return s.EnvVarPrefix + ref
+}
+
+// decodeText decodes values from string to types implementing `encoding.TextUnmarshaler`
+func (s *Settings) decodeText(f reflect.Type, t reflect.Type, v interface{}) (interface{}, error) {
+
+	if f.Kind() != reflect.String || s.textUnmarshalerCheck(t) == false {
+		return v, nil
+	}
+
+	var r reflect.Value
+
+	if t.Kind() == reflect.Ptr {
+		r = reflect.New(t.Elem())
+	} else {
+		r = reflect.New(t)
+	}
+
+	if err := s.textUnmarshalerGet(r.Interface()).UnmarshalText([]byte(v.(string))); err != nil {
+		return v, err
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return r.Interface(), nil
+	}
+
+	return r.Elem().Interface(), nil
+}
+
+// textUnmarshalerCheck checks that values of type `t` (or pointers to it) implement `encoding.TextUnmarshaler`.
+// URLs and types implementing `sql.Scanner` are treated the same way (see `textUnmarshalerGet`)
+func (s *Settings) textUnmarshalerCheck(t reflect.Type) bool {
+
+	tu := reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	if t == urlType || t == reflect.PtrTo(urlType) || s.scannerCheck(t) == true {
+		return true
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return t.Implements(tu)
+	}
+
+	return reflect.PtrTo(t).Implements(tu)
+}
+
+// textUnmarshalerGet returns `encoding.TextUnmarshaler` for pointer `p` to option value
+func (s *Settings) textUnmarshalerGet(p interface{}) encoding.TextUnmarshaler {
+
+	switch v := p.(type) {
+	case *url.URL:
+		return urlText{u: v}
+	case encoding.TextUnmarshaler:
+		return v
+	case sql.Scanner:
+		return scannerText{sc: v}
+	}
+
+	return nil
+}
+
+// decodeFromString decodes values from string to other types.
+func (s *Settings) decodeFromString(f reflect.Type, t reflect.Type, v interface{}) (interface{}, error) {
+
+	if f.Kind() != reflect.String {
+		return v, nil
+	}
+
+	r, err := s.convFromString(v.(string), t)
+	if err != nil && typeErrorCheck(err) == true {
+		return v, fmt.Errorf("expected type '%s', got unconvertible type '%s' value '%s'", t, f, v)
+	}
+
+	return r, err
+}
+
+// convFromString converts string value to other type in accordance to `t`
+func (s *Settings) convFromString(str string, t reflect.Type) (interface{}, error) {
+
+	if t == durationType {
+		d, err := time.ParseDuration(str)
+		return int64(d), err
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return s.boolParse(str)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, ok := enumValueGet(t, str); ok == true {
+			return v, nil
+		}
+		return strconv.ParseInt(strings.ReplaceAll(str, "_", ""), 0, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v, ok := enumValueGet(t, str); ok == true {
+			return uint64(v), nil
+		}
+		return strconv.ParseUint(strings.ReplaceAll(str, "_", ""), 0, t.Bits())
+	case reflect.Float32:
+		return strconv.ParseFloat(str, 32)
+	case reflect.Float64:
+		return strconv.ParseFloat(str, 64)
+	}
+
+	return str, nil
+}
+
+// boolParse parses bool value `str`. Besides values accepted by `strconv.ParseBool`
+// words `yes`, `no`, `on`, `off`, `enabled` and `disabled` are accepted case-insensitively
+func (s *Settings) boolParse(str string) (bool, error) {
+
+	switch strings.ToLower(str) {
+	case "yes", "on", "enabled":
+		return true, nil
+	case "no", "off", "disabled":
+		return false, nil
+	}
+
+	return strconv.ParseBool(str)
+}
+
+// optStrNormalize normalizes string value `str` of option with type `t` in accordance with extra options `tag`
+func (s *Settings) optStrNormalize(str string, t reflect.Type, tag string) string {
+
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		// Comma is a decimal separator, dots and spaces are thousands separators
+		if d, _ := s.tagValGet(tag, tagConfDecimalName); d == "comma" {
+			str = strings.NewReplacer(".", "", " ", "", ",", ".").Replace(str)
+		}
+	case reflect.String:
+		if s.tagKeyCheck(tag, tagConfTrimName) == true {
+			str = strings.TrimSpace(str)
+		}
+		if s.tagKeyCheck(tag, tagConfLowerName) == true {
+			str = strings.ToLower(str)
+		}
+		if s.tagKeyCheck(tag, tagConfUpperName) == true {
+			str = strings.ToUpper(str)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, _ := s.tagValGet(tag, tagConfUnitName)
+
+		switch {
+		case u == "bytes":
+			// Sizes with units (e.g. `10MB`) are converted to number of bytes
+			if n, ok := s.bytesSizeParse(str); ok == true {
+				str = n
+			}
+		case u != "" && t == durationType:
+			// Bare numbers of durations are multiplied by unit (e.g. `30` with `unit=s` is `30s`)
+			if _, err := strconv.ParseFloat(strings.TrimSpace(str), 64); err == nil {
+				str = strings.TrimSpace(str) + u
+			}
+		}
+	}
+
+	return str
+}
+
+// bytesSizeParse converts size `str` with unit suffix (e.g. `10MB` or `1GiB`) to number of bytes.
+// Decimal (KB, MB, GB, TB) and binary (KiB, MiB, GiB, TiB) units are available
+func (s *Settings) bytesSizeParse(str string) (string, bool) {
+
+	units := map[string]uint64{
+		"":    1,
+		"B":   1,
+		"KB":  1000,
+		"MB":  1000 * 1000,
+		"GB":  1000 * 1000 * 1000,
+		"TB":  1000 * 1000 * 1000 * 1000,
+		"KIB": 1 << 10,
+		"MIB": 1 << 20,
+		"GIB": 1 << 30,
+		"TIB": 1 << 40,
+	}
+
+	str = strings.TrimSpace(str)
+
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return unicode.IsDigit(r) == false && r != '_'
+	})
+	if i < 0 {
+		i = len(str)
+	}
+
+	n, err := strconv.ParseUint(strings.ReplaceAll(str[:i], "_", ""), 10, 64)
+	if err != nil {
+		return str, false
+	}
+
+	m, ok := units[strings.ToUpper(strings.TrimSpace(str[i:]))]
+	if ok == false || n > ^uint64(0)/m {
+		return str, false
+	}
+
+	return strconv.FormatUint(n*m, 10), true
+}
+
+// convValue converts string value to value of scalar type `t`
+func (s *Settings) convValue(str string, t reflect.Type) (reflect.Value, error) {
+
+	d, err := s.convFromString(str, t)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	v := reflect.ValueOf(d)
+	if v.Type().ConvertibleTo(t) == false {
+		return reflect.Value{}, fmt.Errorf("unable to convert '%s' to type `%s`", str, t)
+	}
+
+	return v.Convert(t), nil
+}
+
+// fieldNameNormalize returns either name from tag if specified, or struct field name as is
+func (s *Settings) fieldNameNormalize(tf reflect.StructField) string {
+
+	tag := tf.Tag.Get(tagConfName)
+
+	str := s.tagValIndexGet(tag, 0)
+	if str != "" {
+		return str
+	}
+
+	return tf.Name
+}
+
+// optIsUsed checks that option with path `opt` is specified in config file
+func (s *Settings) optIsUsed(opt string) bool {
+	return s.used[opt]
+}
+
+// optIsNull checks that option with path `opt` is explicitly specified in config file with null value
+func (s *Settings) optIsNull(opt string) bool {
+	return s.nulls[opt]
+}
+
+// fieldRemainCheck checks struct field `tf` is marked to collect unmatched options (`conf:",remain"`)
+func (s *Settings) fieldRemainCheck(tf reflect.StructField) bool {
+
+	p := strings.Split(tf.Tag.Get(tagConfName), ",")
+
+	for _, e := range p[1:] {
+		if e == "remain" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// optNameJoin returns path of option for struct field `tf` within parent option `parentName`
+func (s *Settings) optNameJoin(parentName string, tf reflect.StructField) string {
+
+	// Options of squashed structs are treated as parent struct options
+	if s.fieldSquashCheck(tf) == true {
+		return parentName
+	}
+
+	return s.optPathJoin(parentName, s.fieldNameNormalize(tf))
+}
+
+// fieldSquashCheck checks struct field `tf` is squashed (`conf:",squash"`), so its sub-options are specified at the parent level
+func (s *Settings) fieldSquashCheck(tf reflect.StructField) bool {
+
+	p := strings.Split(tf.Tag.Get(tagConfName), ",")
+
+	for _, e := range p[1:] {
+		if e == "squash" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// optPathJoin returns path of option with name `name` within parent option `parentName`.
+// Dots within option names are escaped to keep path unambiguous
+func (s *Settings) optPathJoin(parentName string, name string) string {
+
+	name = strings.ReplaceAll(name, ".", `\.`)
+
+	if parentName == "" {
+		return name
+	}
+
+	return strings.Join([]string{parentName, name}, ".")
+}
+
+// optPathIndexesStrip removes indexes of slices and maps elements from option path `path`
+// (e.g. `backends[0].port` is `backends.port`)
+func (s *Settings) optPathIndexesStrip(path string) string {
+	return optPathIndexRegexp.ReplaceAllString(path, "")
+}
+
+// optPathSplit splits option path `path` by unescaped dots
+func (s *Settings) optPathSplit(path string) []string {
+
+	var (
+		p []string
+		b strings.Builder
+	)
+
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			b.WriteByte('.')
+			i++
+		case path[i] == '.':
+			p = append(p, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(path[i])
+		}
+	}
+
+	return append(p, b.String())
+}
+
+// usedOptsCollect collects paths of options specified in raw config data with non-null and null values
+func (s *Settings) usedOptsCollect(rawConf map[string]interface{}, t reflect.Type) error {
+
+	s.used = make(map[string]bool)
+	s.nulls = make(map[string]bool)
+
+	return s.walkRaw(rawConf, t, "", func(m reflect.Value, k reflect.Value, tf reflect.StructField, elName string) error {
+		if m.MapIndex(k).Interface() != nil {
+			s.used[elName] = true
+		} else {
+			s.nulls[elName] = true
+		}
+		return nil
+	})
+}
+
+// tagPartsMakeMap prepairs map for tag pairs
 func (s *Settings) tagPartsMakeMap(tag string) map[string]string {
 
 	tm := make(map[string]string)
 
-	p := strings.Split(tag, ",")
+	p := s.tagPartsSplit(tag)
 
 	for _, e := range p {
-		s := strings.Split(e, "=")
+		s := strings.SplitN(e, "=", 2)
 		if len(s) > 1 {
 			tm[strings.Trim(s[0], " \t")] = s[1]
 		} else {
@@ -369,6 +3260,53 @@ func (s *Settings) tagPartsMakeMap(tag string) map[string]string {
 	return tm
 }
 
+// tagPartsSplit splits `tag` by commas. Escaped commas (`\,`) are kept within parts unescaped,
+// commas within values enclosed in single quotes (e.g. `default='a,b'`) are kept as is and quotes are removed
+func (s *Settings) tagPartsSplit(tag string) []string {
+
+	var (
+		p []string
+		b strings.Builder
+	)
+
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			b.WriteByte(',')
+			i++
+		case tag[i] == '\'' && strings.HasSuffix(b.String(), "=") == true && strings.Count(b.String(), "=") == 1:
+			// Value enclosed in single quotes is taken as is
+			j := s.tagQuoteEnd(tag, i)
+			if j < 0 {
+				b.WriteByte(tag[i])
+				continue
+			}
+			b.WriteString(tag[i+1 : j])
+			i = j
+		case tag[i] == ',':
+			p = append(p, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(tag[i])
+		}
+	}
+
+	return append(p, b.String())
+}
+
+// tagQuoteEnd returns index of single quote closing value started at index `start` within `tag`,
+// or -1 if value is not closed. Closing quote must be followed by comma or the end of tag
+func (s *Settings) tagQuoteEnd(tag string, start int) int {
+
+	for j := start + 1; j < len(tag); j++ {
+		if tag[j] == '\'' && (j+1 == len(tag) || tag[j+1] == ',') {
+			return j
+		}
+	}
+
+	return -1
+}
+
 // tagKeyCheck cheks that `tag` contains `key`
 func (s *Settings) tagKeyCheck(tag string, key string) bool {
 
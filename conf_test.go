@@ -0,0 +1,2957 @@
+package conf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// testLoadYAML writes YAML document `data` into temporary config file and loads it into `conf`
+func testLoadYAML(t *testing.T, data string, conf interface{}, s Settings) error {
+
+	s.ConfPath = filepath.Join(t.TempDir(), "conf.yml")
+	s.ConfType = ConfigTypeYAML
+
+	if err := ioutil.WriteFile(s.ConfPath, []byte(data), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	return Load(conf, s)
+}
+
+func TestDecodeHooks(t *testing.T) {
+
+	type tConfOut struct {
+		Size  int    `conf:"size"`
+		Limit int    `conf:"limit"`
+		Env   int    `conf:"env"`
+		Name  string `conf:"name"`
+	}
+
+	// kbHook converts strings like `1kb` into an int
+	kbHook := func(f reflect.Type, t reflect.Type, v interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t.Kind() != reflect.Int {
+			return v, nil
+		}
+		str := v.(string)
+		if strings.HasSuffix(str, "kb") == false {
+			return v, nil
+		}
+		i, err := strconv.Atoi(strings.TrimSuffix(str, "kb"))
+		if err != nil {
+			return v, err
+		}
+		return i * 1024, nil
+	}
+
+	var c tConfOut
+
+	os.Setenv("TEST_CONF_HOOK_SIZE", "2kb")
+
+	if err := testLoadYAML(t, "size: 1kb\nlimit: \"10\"\nenv: ENV:TEST_CONF_HOOK_SIZE\nname: test\n", &c, Settings{
+		DecodeHooks: []mapstructure.DecodeHookFunc{kbHook},
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Size != 1024 {
+		t.Fatal("Incorrect loaded data: Size")
+	}
+
+	if c.Limit != 10 {
+		t.Fatal("Incorrect loaded data: Limit")
+	}
+
+	// Check hook receives value of ENV variable
+	if c.Env != 2048 {
+		t.Fatal("Incorrect loaded data: Env")
+	}
+
+	if c.Name != "test" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+}
+
+func TestEncryptedOpts(t *testing.T) {
+
+	type tConfOut struct {
+		Password string `conf:"password" conf_extraopts:"encrypted"`
+		Name     string `conf:"name"`
+		Servers  []struct {
+			Token string `conf:"token" conf_extraopts:"encrypted"`
+		} `conf:"servers"`
+	}
+
+	// xorDecryptor is a trivial decryptor for testing purposes
+	xorDecryptor := func(b []byte) ([]byte, error) {
+		d := make([]byte, len(b))
+		for i := range b {
+			d[i] = b[i] ^ 0x01
+		}
+		return d, nil
+	}
+
+	var c tConfOut
+
+	// `rdbsdu` is `secret` encrypted by xor with 0x01
+	if err := testLoadYAML(t, "password: rdbsdu\nname: rdbsdu\nservers:\n- token: unjdo\n", &c, Settings{
+		Decryptor: xorDecryptor,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Password != "secret" {
+		t.Fatal("Incorrect loaded data: Password")
+	}
+
+	// Check unmarked option is not decrypted
+	if c.Name != "rdbsdu" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	if c.Servers[0].Token != "token" {
+		t.Fatal("Incorrect loaded data: Servers[0].Token")
+	}
+
+	// Check decrypt errors contain option path
+	err := testLoadYAML(t, "password: rdbsdu\n", &c, Settings{
+		Decryptor: func(b []byte) ([]byte, error) {
+			return nil, fmt.Errorf("bad key")
+		},
+	})
+	if err == nil || strings.Contains(err.Error(), "'password'") == false {
+		t.Fatal("Incorrect decrypt error:", err)
+	}
+}
+
+func TestDefaultIfSet(t *testing.T) {
+
+	type tConfOut struct {
+		TLS struct {
+			CertFile string `conf:"cert_file"`
+			Enabled  bool   `conf:"enabled" conf_extraopts:"default_if_set=cert_file"`
+		} `conf:"tls"`
+	}
+
+	var c tConfOut
+
+	// Check sibling option is present
+	if err := testLoadYAML(t, "tls:\n  cert_file: /tmp/cert.pem\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.TLS.Enabled != true {
+		t.Fatal("Incorrect loaded data: TLS.Enabled")
+	}
+
+	// Check sibling option is absent
+	c = tConfOut{}
+
+	if err := testLoadYAML(t, "tls: {}\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.TLS.Enabled != false {
+		t.Fatal("Incorrect loaded data: TLS.Enabled")
+	}
+
+	// Check explicitly specified value is kept
+	c = tConfOut{}
+
+	if err := testLoadYAML(t, "tls:\n  cert_file: /tmp/cert.pem\n  enabled: false\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.TLS.Enabled != false {
+		t.Fatal("Incorrect loaded data: TLS.Enabled")
+	}
+}
+
+func TestDefaultEnv(t *testing.T) {
+
+	type tConfOut struct {
+		Home string `conf:"home" conf_extraopts:"default=ENV:TEST_CONF_DEFAULT_HOME"`
+		Port int    `conf:"port" conf_extraopts:"default=ENV:TEST_CONF_DEFAULT_PORT"`
+	}
+
+	var c tConfOut
+
+	os.Setenv("TEST_CONF_DEFAULT_HOME", "/home/test")
+	os.Setenv("TEST_CONF_DEFAULT_PORT", "8080")
+
+	if err := testLoadYAML(t, "{}\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Home != "/home/test" {
+		t.Fatal("Incorrect loaded data: Home")
+	}
+
+	if c.Port != 8080 {
+		t.Fatal("Incorrect loaded data: Port")
+	}
+
+	// Check unset ENV variable in default value
+	os.Unsetenv("TEST_CONF_DEFAULT_PORT")
+
+	c = tConfOut{}
+
+	if err := testLoadYAML(t, "{}\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected error for unset ENV variable in default value")
+	}
+
+	// Check unset ENV variable is not used if option is specified
+	c = tConfOut{}
+
+	if err := testLoadYAML(t, "port: 80\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Port != 80 {
+		t.Fatal("Incorrect loaded data: Port")
+	}
+}
+
+func TestStrictOpts(t *testing.T) {
+
+	type tConfOut struct {
+		Port    int    `conf:"port"`
+		Name    string `conf:"name"`
+		Workers int    `conf:"workers" conf_extraopts:"strict"`
+		Label   string `conf:"label" conf_extraopts:"strict"`
+	}
+
+	var c tConfOut
+
+	os.Setenv("TEST_CONF_STRICT_WORKERS", "4")
+
+	// Check weak conversions for not strict options and ENV variables for strict options
+	if err := testLoadYAML(t, "port: \"8080\"\nname: 123\nworkers: ENV:TEST_CONF_STRICT_WORKERS\nlabel: test\n", &c, Settings{
+		WeaklyTypes: true,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Port != 8080 {
+		t.Fatal("Incorrect loaded data: Port")
+	}
+
+	if c.Name != "123" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	if c.Workers != 4 {
+		t.Fatal("Incorrect loaded data: Workers")
+	}
+
+	// Check strict options reject conversions
+	for _, d := range []string{
+		"workers: \"4\"\n",
+		"label: 123\n",
+	} {
+		if err := testLoadYAML(t, d, &c, Settings{
+			WeaklyTypes: true,
+		}); err == nil {
+			t.Fatalf("Expected strict option error for `%s`", d)
+		}
+	}
+}
+
+func TestSortedBy(t *testing.T) {
+
+	type tConfOut struct {
+		Stages []struct {
+			Name     string `conf:"name"`
+			Priority int    `conf:"priority"`
+		} `conf:"stages" conf_extraopts:"sorted_by=priority"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "stages:\n- {name: a, priority: 1}\n- {name: b, priority: 1}\n- {name: c, priority: 5}\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	c = tConfOut{}
+
+	err := testLoadYAML(t, "stages:\n- {name: a, priority: 1}\n- {name: b, priority: 5}\n- {name: c, priority: 3}\n", &c, Settings{})
+	if err == nil || strings.Contains(err.Error(), "at index 2") == false {
+		t.Fatal("Incorrect sorted_by error:", err)
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name" conf_extraopts:"required"`
+		Port int    `conf:"port" conf_extraopts:"default=8080"`
+	}
+
+	fsys := fstest.MapFS{
+		"configs/app.yml": &fstest.MapFile{
+			Data: []byte("name: test\n"),
+		},
+	}
+
+	var c tConfOut
+
+	if err := LoadFS(&c, fsys, "configs/app.yml", Settings{
+		ConfType: ConfigTypeYAML,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "test" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	if c.Port != 8080 {
+		t.Fatal("Incorrect loaded data: Port")
+	}
+
+	// Check missing file
+	if err := LoadFS(&c, fsys, "configs/missing.yml", Settings{
+		ConfType: ConfigTypeYAML,
+	}); err == nil {
+		t.Fatal("Expected error for missing file")
+	}
+}
+
+func TestLoadBytes(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name" conf_extraopts:"required"`
+	}
+
+	var c tConfOut
+
+	if err := LoadBytes(&c, []byte(`{"name": "test"}`), Settings{
+		ConfType: ConfigTypeJSON,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "test" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+}
+
+func TestStrictSubtree(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name"`
+		DB   struct {
+			Host string `conf:"host"`
+			Port int    `conf:"port"`
+		} `conf:"db" conf_extraopts:"strict"`
+	}
+
+	var c tConfOut
+
+	// Check unknown options are allowed outside the strict subtree
+	if err := testLoadYAML(t, "name: test\nextra: value\ndb:\n  host: localhost\n  port: 5432\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.DB.Host != "localhost" || c.DB.Port != 5432 {
+		t.Fatal("Incorrect loaded data: DB")
+	}
+
+	// Check unknown options are denied within the strict subtree
+	err := testLoadYAML(t, "name: test\ndb:\n  host: localhost\n  stray: value\n", &c, Settings{})
+	if err == nil || strings.Contains(err.Error(), "'db.stray'") == false {
+		t.Fatal("Incorrect strict subtree error:", err)
+	}
+}
+
+func TestStrictSubtreeConversions(t *testing.T) {
+
+	RegisterEnum(tEnumState(0), map[string]int64{
+		"inactive": int64(tEnumStateInactive),
+		"active":   int64(tEnumStateActive),
+	})
+
+	type tConfOut struct {
+		Sub struct {
+			Timeout time.Duration `conf:"timeout"`
+			State   tEnumState    `conf:"state"`
+			Buffer  int           `conf:"buffer" conf_extraopts:"unit=bytes"`
+		} `conf:"sub" conf_extraopts:"strict"`
+	}
+
+	var c tConfOut
+
+	// Sub-options of strict subtree are decoded as usual
+	if err := testLoadYAML(t, "sub:\n  timeout: 30s\n  state: active\n  buffer: 4KiB\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Sub.Timeout != 30*time.Second || c.Sub.State != tEnumStateActive || c.Sub.Buffer != 4096 {
+		t.Fatal("Incorrect loaded data: Sub")
+	}
+
+	err := testLoadYAML(t, "sub:\n  timeout: 30s\n  stray: value\n", &c, Settings{})
+	if err == nil || strings.Contains(err.Error(), "unknown option 'sub.stray'") == false {
+		t.Fatal("Incorrect strict subtree error:", err)
+	}
+}
+
+func TestDefaultKey(t *testing.T) {
+
+	type tConfOut struct {
+		Server struct {
+			DefaultTimeout int `conf:"default_timeout" conf_extraopts:"default=30"`
+		} `conf:"server"`
+		Clients struct {
+			API struct {
+				Timeout int `conf:"timeout" conf_extraopts:"default_key=server.default_timeout"`
+			} `conf:"api"`
+		} `conf:"clients"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "server:\n  default_timeout: 10\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Clients.API.Timeout != 10 {
+		t.Fatal("Incorrect loaded data: Clients.API.Timeout")
+	}
+
+	// Check source option default value is used
+	c = tConfOut{}
+
+	if err := testLoadYAML(t, "{}\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Clients.API.Timeout != 30 {
+		t.Fatal("Incorrect loaded data: Clients.API.Timeout")
+	}
+
+	// Check specified value is kept
+	c = tConfOut{}
+
+	if err := testLoadYAML(t, "clients:\n  api:\n    timeout: 5\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Clients.API.Timeout != 5 {
+		t.Fatal("Incorrect loaded data: Clients.API.Timeout")
+	}
+
+	// Check missing source option
+	type tConfMissing struct {
+		Timeout int `conf:"timeout" conf_extraopts:"default_key=server.missing"`
+	}
+
+	var cm tConfMissing
+
+	if err := testLoadYAML(t, "{}\n", &cm, Settings{}); err == nil {
+		t.Fatal("Expected error for missing source option")
+	}
+}
+
+func TestConfigTypeAuto(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name" conf_extraopts:"required"`
+	}
+
+	dir := t.TempDir()
+
+	for f, d := range map[string]string{
+		"conf.yaml": "name: test\n",
+		"conf.yml":  "name: test\n",
+		"conf.json": "{\"name\": \"test\"}",
+	} {
+
+		var c tConfOut
+
+		p := filepath.Join(dir, f)
+
+		if err := ioutil.WriteFile(p, []byte(d), 0644); err != nil {
+			t.Fatal("Config file prepare error:", err)
+		}
+
+		if err := Load(&c, Settings{
+			ConfPath: p,
+			ConfType: ConfigTypeAuto,
+		}); err != nil {
+			t.Fatalf("Config load error for `%s`: %v", f, err)
+		}
+
+		if c.Name != "test" {
+			t.Fatalf("Incorrect loaded data for `%s`: Name", f)
+		}
+	}
+
+	// Check unknown extension
+	var c tConfOut
+
+	p := filepath.Join(dir, "conf.txt")
+
+	if err := ioutil.WriteFile(p, []byte("name: test\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	if err := Load(&c, Settings{
+		ConfPath: p,
+		ConfType: ConfigTypeAuto,
+	}); err == nil {
+		t.Fatal("Expected error for unknown config file extension")
+	}
+
+	// Check data without file name
+	for _, d := range []string{"{\"name\": \"test\"}", "name: test\n"} {
+
+		c = tConfOut{}
+
+		if err := LoadBytes(&c, []byte(d), Settings{
+			ConfType: ConfigTypeAuto,
+		}); err != nil {
+			t.Fatal("Config load error:", err)
+		}
+
+		if c.Name != "test" {
+			t.Fatal("Incorrect loaded data: Name")
+		}
+	}
+}
+
+func TestNotEmpty(t *testing.T) {
+
+	type tConfOut struct {
+		Name  string   `conf:"name" conf_extraopts:"notempty"`
+		Hosts []string `conf:"hosts" conf_extraopts:"notempty"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "name: test\nhosts: [localhost]\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "test" || len(c.Hosts) != 1 {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	for d, o := range map[string]string{
+		"name: \"\"\nhosts: [localhost]\n": "name",
+		"name: test\nhosts: []\n":          "hosts",
+		"hosts: [localhost]\n":             "name",
+	} {
+
+		c = tConfOut{}
+
+		err := testLoadYAML(t, d, &c, Settings{})
+		if err == nil || strings.Contains(err.Error(), "'"+o+"'") == false {
+			t.Fatalf("Incorrect notempty error for `%s`: %v", d, err)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+
+	var c map[string]interface{}
+
+	d := "a:\n  b:\n    c:\n      d: value\n"
+
+	if err := testLoadYAML(t, d, &c, Settings{
+		MaxDepth: 4,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if err := testLoadYAML(t, d, &c, Settings{
+		MaxDepth: 3,
+	}); err == nil {
+		t.Fatal("Expected error for config exceeding maximum depth")
+	}
+
+	if err := testLoadYAML(t, "a: [[[value]]]\n", &c, Settings{
+		MaxDepth: 3,
+	}); err == nil {
+		t.Fatal("Expected error for config exceeding maximum depth")
+	}
+}
+
+func TestMaxDepthRecursive(t *testing.T) {
+
+	type tConfNode struct {
+		Name string     `conf:"name" conf_extraopts:"default=node"`
+		Next *tConfNode `conf:"next"`
+	}
+
+	// Self-referential struct specified deeper than the default limit
+	d := strings.Repeat("{next: ", 70) + "{}" + strings.Repeat("}", 70) + "\n"
+
+	err := testLoadYAML(t, d, &tConfNode{}, Settings{})
+	if err == nil || strings.Contains(err.Error(), "exceeds maximum nesting depth 64") == false {
+		t.Fatal("Incorrect maximum depth error:", err)
+	}
+
+	// Deep config exceeds the default limit
+	d = strings.Repeat("{a: ", 70) + "value" + strings.Repeat("}", 70) + "\n"
+
+	var c map[string]interface{}
+
+	err = testLoadYAML(t, d, &c, Settings{})
+	if err == nil || strings.Contains(err.Error(), "exceeds maximum nesting depth 64") == false {
+		t.Fatal("Incorrect maximum depth error:", err)
+	}
+}
+
+func TestReadonlyWarnings(t *testing.T) {
+
+	type tConfOut struct {
+		BufferSize int    `conf:"buffer_size" conf_extraopts:"readonly,default=4096"`
+		Mode       string `conf:"mode" conf_extraopts:"readonly"`
+	}
+
+	dir := t.TempDir()
+
+	for d, n := range map[string]int{
+		"{}\n":                              0,
+		"buffer_size: 4096\n":               0,
+		"buffer_size: 8192\n":               1,
+		"buffer_size: 8192\nmode: manual\n": 2,
+	} {
+
+		var c tConfOut
+
+		p := filepath.Join(dir, "conf.yml")
+
+		if err := ioutil.WriteFile(p, []byte(d), 0644); err != nil {
+			t.Fatal("Config file prepare error:", err)
+		}
+
+		w, err := LoadWithWarnings(&c, Settings{
+			ConfPath: p,
+			ConfType: ConfigTypeYAML,
+		})
+		if err != nil {
+			t.Fatal("Config load error:", err)
+		}
+
+		if len(w) != n {
+			t.Fatalf("Incorrect warnings for `%s`: %v", d, w)
+		}
+	}
+}
+
+type tValidatorRange struct {
+	Start int `conf:"start"`
+	End   int `conf:"end"`
+}
+
+func (r *tValidatorRange) Validate() error {
+	if r.Start >= r.End {
+		return fmt.Errorf("start must be less than end")
+	}
+	return nil
+}
+
+type tValidatorConf struct {
+	Ranges []tValidatorRange `conf:"ranges"`
+	Window tValidatorRange   `conf:"window"`
+	TLS    bool              `conf:"tls"`
+	Cert   string            `conf:"cert"`
+}
+
+func (c *tValidatorConf) Validate() error {
+	if c.TLS == true && c.Cert == "" {
+		return fmt.Errorf("cert must be specified if tls is enabled")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+
+	var c tValidatorConf
+
+	if err := testLoadYAML(t, "window: {start: 1, end: 2}\nranges:\n- {start: 1, end: 5}\ntls: true\ncert: /tmp/cert.pem\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	for d, e := range map[string]string{
+		"window: {start: 1, end: 2}\ntls: true\n":                           "cert must be specified",
+		"window: {start: 3, end: 2}\n":                                      "option 'window'",
+		"window: {start: 1, end: 2}\nranges:\n- {start: 1, end: 5}\n- {}\n": "option 'ranges[1]'",
+	} {
+
+		c = tValidatorConf{}
+
+		err := testLoadYAML(t, d, &c, Settings{})
+		if err == nil || strings.Contains(err.Error(), e) == false {
+			t.Fatalf("Incorrect validation error for `%s`: %v", d, err)
+		}
+	}
+}
+
+func TestRequiredCheckFirst(t *testing.T) {
+
+	type tConfOut struct {
+		Password string `conf:"password"`
+		DB       struct {
+			Host string `conf:"host" conf_extraopts:"required"`
+		} `conf:"db" conf_extraopts:"required"`
+	}
+
+	hookCalls := 0
+
+	// countHook counts decode hook calls
+	countHook := func(f reflect.Type, t reflect.Type, v interface{}) (interface{}, error) {
+		hookCalls++
+		return v, nil
+	}
+
+	os.Unsetenv("TEST_CONF_REQUIRED_FIRST_PASSWORD")
+
+	var c tConfOut
+
+	err := testLoadYAML(t, "password: ENV:TEST_CONF_REQUIRED_FIRST_PASSWORD\ndb: {}\n", &c, Settings{
+		RequiredCheckFirst: true,
+		DecodeHooks:        []mapstructure.DecodeHookFunc{countHook},
+	})
+	if err == nil || strings.Contains(err.Error(), "required option 'db.host'") == false {
+		t.Fatal("Incorrect required option error:", err)
+	}
+
+	if hookCalls != 0 {
+		t.Fatal("Decode hooks are called before required options check")
+	}
+
+	// Absent non-pointer nested struct with required sub-options fails fast too
+	type tConfNested struct {
+		Password string `conf:"password"`
+		Cache    struct {
+			Backend struct {
+				Addr string `conf:"addr" conf_extraopts:"required"`
+			} `conf:"backend"`
+		} `conf:"cache"`
+	}
+
+	hookCalls = 0
+
+	err = testLoadYAML(t, "password: ENV:TEST_CONF_REQUIRED_FIRST_PASSWORD\n", &tConfNested{}, Settings{
+		RequiredCheckFirst: true,
+		DecodeHooks:        []mapstructure.DecodeHookFunc{countHook},
+	})
+	if err == nil || strings.Contains(err.Error(), "required option 'cache.backend.addr' is not specified") == false {
+		t.Fatal("Incorrect required option error:", err)
+	}
+
+	if hookCalls != 0 {
+		t.Fatal("Decode hooks are called before required options check")
+	}
+
+	// Check ENV variables are resolved if all required options are present
+	os.Setenv("TEST_CONF_REQUIRED_FIRST_PASSWORD", "secret")
+
+	if err := testLoadYAML(t, "password: ENV:TEST_CONF_REQUIRED_FIRST_PASSWORD\ndb:\n  host: localhost\n", &c, Settings{
+		RequiredCheckFirst: true,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Password != "secret" {
+		t.Fatal("Incorrect loaded data: Password")
+	}
+}
+
+func TestSliceOfPointersDefaults(t *testing.T) {
+
+	type tItem struct {
+		Name string `conf:"name" conf_extraopts:"required"`
+		Port int    `conf:"port" conf_extraopts:"default=8080"`
+	}
+
+	type tConfOut struct {
+		Items []*tItem `conf:"items"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "items:\n- name: a\n- null\n- name: c\n  port: 9090\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if len(c.Items) != 3 {
+		t.Fatal("Incorrect loaded data: Items")
+	}
+
+	if c.Items[0].Port != 8080 {
+		t.Fatal("Incorrect loaded data: Items[0].Port")
+	}
+
+	if c.Items[1] != nil {
+		t.Fatal("Incorrect loaded data: Items[1]")
+	}
+
+	if c.Items[2].Port != 9090 {
+		t.Fatal("Incorrect loaded data: Items[2].Port")
+	}
+}
+
+func TestDefaultOnZero(t *testing.T) {
+
+	type tConfOut struct {
+		Retries int `conf:"retries" conf_extraopts:"default=3,default_on_zero"`
+		Workers int `conf:"workers" conf_extraopts:"default=3"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "retries: 0\nworkers: 0\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Retries != 3 {
+		t.Fatal("Incorrect loaded data: Retries")
+	}
+
+	// Check option without `default_on_zero` keeps zero value
+	if c.Workers != 0 {
+		t.Fatal("Incorrect loaded data: Workers")
+	}
+
+	c = tConfOut{}
+
+	if err := testLoadYAML(t, "retries: 5\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Retries != 5 {
+		t.Fatal("Incorrect loaded data: Retries")
+	}
+}
+
+func TestDecimalComma(t *testing.T) {
+
+	type tConfOut struct {
+		Ratio    float64 `conf:"ratio" conf_extraopts:"decimal=comma"`
+		Amount   float64 `conf:"amount" conf_extraopts:"decimal=comma"`
+		Env      float64 `conf:"env" conf_extraopts:"decimal=comma"`
+		Standard float64 `conf:"standard"`
+	}
+
+	var c tConfOut
+
+	os.Setenv("TEST_CONF_DECIMAL_COMMA", "0,25")
+
+	if err := testLoadYAML(t, "ratio: 1,5\namount: 1.234.567,89\nenv: ENV:TEST_CONF_DECIMAL_COMMA\nstandard: 1.5\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Ratio != 1.5 {
+		t.Fatal("Incorrect loaded data: Ratio")
+	}
+
+	if c.Amount != 1234567.89 {
+		t.Fatal("Incorrect loaded data: Amount")
+	}
+
+	if c.Env != 0.25 {
+		t.Fatal("Incorrect loaded data: Env")
+	}
+
+	if c.Standard != 1.5 {
+		t.Fatal("Incorrect loaded data: Standard")
+	}
+
+	// Check numeric value from file with comma decimal option
+	c = tConfOut{}
+
+	if err := testLoadYAML(t, "ratio: 1.5\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Ratio != 1.5 {
+		t.Fatal("Incorrect loaded data: Ratio")
+	}
+}
+
+func TestEmptyValueWarnings(t *testing.T) {
+
+	type tConfOut struct {
+		Host string `conf:"host" conf_extraopts:"default=localhost"`
+		User string `conf:"user" conf_extraopts:"default=root,default_on_zero"`
+		Name string `conf:"name"`
+	}
+
+	dir := t.TempDir()
+
+	for d, n := range map[string]int{
+		"host: example.com\n":              0,
+		"{}\n":                             0,
+		"host: \"\"\n":                     1,
+		"user: \"\"\nname: \"\"\n":         0,
+		"host: \"\"\nuser: \"\"\n":         1,
+		"host: \"\"\nuser: admin\nname: a": 1,
+	} {
+
+		var c tConfOut
+
+		p := filepath.Join(dir, "conf.yml")
+
+		if err := ioutil.WriteFile(p, []byte(d), 0644); err != nil {
+			t.Fatal("Config file prepare error:", err)
+		}
+
+		w, err := LoadWithWarnings(&c, Settings{
+			ConfPath: p,
+			ConfType: ConfigTypeYAML,
+		})
+		if err != nil {
+			t.Fatal("Config load error:", err)
+		}
+
+		if len(w) != n {
+			t.Fatalf("Incorrect warnings for `%s`: %v", d, w)
+		}
+	}
+}
+
+func TestMapDefaults(t *testing.T) {
+
+	type tConfOut struct {
+		Servers map[string]struct {
+			Host    string `conf:"host" conf_extraopts:"required"`
+			Port    int    `conf:"port" conf_extraopts:"default=8080"`
+			Proto   string `conf:"proto" conf_extraopts:"default=http"`
+			Enabled bool   `conf:"enabled" conf_extraopts:"default=true"`
+		} `conf:"servers"`
+	}
+
+	d := `
+servers:
+  a.b:
+    host: host1
+    port: 9090
+  c[d]:
+    host: host2
+    enabled: false
+  plain:
+    host: host3
+    proto: https
+`
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, d, &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	for k, e := range map[string]struct {
+		host    string
+		port    int
+		proto   string
+		enabled bool
+	}{
+		"a.b":   {"host1", 9090, "http", true},
+		"c[d]":  {"host2", 8080, "http", false},
+		"plain": {"host3", 8080, "https", true},
+	} {
+		v := c.Servers[k]
+		if v.Host != e.host || v.Port != e.port || v.Proto != e.proto || v.Enabled != e.enabled {
+			t.Fatalf("Incorrect loaded data: Servers[%s]: %+v", k, v)
+		}
+	}
+
+	// Check required option within map element with special characters in key
+	c = tConfOut{}
+
+	err := testLoadYAML(t, "servers:\n  a.b:\n    port: 1\n", &c, Settings{})
+	if err == nil || strings.Contains(err.Error(), "'servers[a.b].host'") == false {
+		t.Fatal("Incorrect required option error:", err)
+	}
+}
+
+func TestDottedNames(t *testing.T) {
+
+	type tConfOut struct {
+		My struct {
+			Key string `conf:"key"`
+		} `conf:"my"`
+		MyKey   string `conf:"my.key" conf_extraopts:"required"`
+		Timeout int    `conf:"timeout.sec" conf_extraopts:"default=10"`
+		Copy    int    `conf:"copy" conf_extraopts:"default_key=timeout\\.sec"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "my.key: value\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.MyKey != "value" {
+		t.Fatal("Incorrect loaded data: MyKey")
+	}
+
+	if c.Timeout != 10 || c.Copy != 10 {
+		t.Fatal("Incorrect loaded data: Timeout")
+	}
+
+	// Check nested option with the same path does not satisfy option with dotted name
+	c = tConfOut{}
+
+	if err := testLoadYAML(t, "my:\n  key: value\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected error for missing required option with dotted name")
+	}
+}
+
+type tTextLevel struct {
+	level int
+}
+
+func (l *tTextLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		l.level = 1
+	case "high":
+		l.level = 2
+	default:
+		return fmt.Errorf("unknown level '%s'", text)
+	}
+	return nil
+}
+
+func TestTextUnmarshalerDefaults(t *testing.T) {
+
+	type tConfOut struct {
+		Level      tTextLevel  `conf:"level" conf_extraopts:"default=high"`
+		LevelPtr   *tTextLevel `conf:"level_ptr" conf_extraopts:"default=low"`
+		LevelFile  tTextLevel  `conf:"level_file" conf_extraopts:"default=high"`
+		LevelNoDef tTextLevel  `conf:"level_no_def"`
+		Addr       net.IP      `conf:"addr" conf_extraopts:"default=127.0.0.1"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "level_file: low\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Level.level != 2 {
+		t.Fatal("Incorrect loaded data: Level")
+	}
+
+	if c.LevelPtr == nil || c.LevelPtr.level != 1 {
+		t.Fatal("Incorrect loaded data: LevelPtr")
+	}
+
+	if c.LevelFile.level != 1 {
+		t.Fatal("Incorrect loaded data: LevelFile")
+	}
+
+	if c.LevelNoDef.level != 0 {
+		t.Fatal("Incorrect loaded data: LevelNoDef")
+	}
+
+	if c.Addr.Equal(net.IPv4(127, 0, 0, 1)) == false {
+		t.Fatal("Incorrect loaded data: Addr")
+	}
+
+	// Check invalid value
+	if err := testLoadYAML(t, "level: medium\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected error for invalid value")
+	}
+}
+
+func TestSecretResolver(t *testing.T) {
+
+	type tConfOut struct {
+		Password string `conf:"password"`
+		Port     int    `conf:"port"`
+		Token    string `conf:"token" conf_extraopts:"default=VAULT:api/token"`
+		Name     string `conf:"name"`
+	}
+
+	secrets := map[string]string{
+		"db/password": "secret",
+		"db/port":     "5432",
+		"api/token":   "token",
+	}
+
+	// resolver is a fake secrets backend
+	resolver := func(ref string) (string, error) {
+		v, ok := secrets[ref]
+		if ok == false {
+			return "", fmt.Errorf("secret not found")
+		}
+		return v, nil
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "password: VAULT:db/password\nport: VAULT:db/port\nname: test\n", &c, Settings{
+		SecretResolver: resolver,
+		SecretPrefix:   "VAULT:",
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Password != "secret" {
+		t.Fatal("Incorrect loaded data: Password")
+	}
+
+	if c.Port != 5432 {
+		t.Fatal("Incorrect loaded data: Port")
+	}
+
+	if c.Token != "token" {
+		t.Fatal("Incorrect loaded data: Token")
+	}
+
+	if c.Name != "test" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	// Check default prefix and unknown secret
+	err := testLoadYAML(t, "password: SECRET:db/unknown\n", &c, Settings{
+		SecretResolver: resolver,
+	})
+	if err == nil || strings.Contains(err.Error(), "db/unknown") == false {
+		t.Fatal("Incorrect secret resolve error:", err)
+	}
+}
+
+func TestMapRequired(t *testing.T) {
+
+	type tServer struct {
+		Host string `conf:"host" conf_extraopts:"required"`
+		Port int    `conf:"port"`
+	}
+
+	type tConfOut struct {
+		Servers     map[string]tServer  `conf:"servers"`
+		ServersPtrs map[string]*tServer `conf:"servers_ptrs"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "servers:\n  web:\n    host: localhost\nservers_ptrs:\n  db:\n    host: localhost\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	for d, e := range map[string]string{
+		"servers:\n  web:\n    port: 80\n":       "'servers[web].host'",
+		"servers_ptrs:\n  db:\n    port: 5432\n": "'servers_ptrs[db].host'",
+	} {
+
+		c = tConfOut{}
+
+		err := testLoadYAML(t, d, &c, Settings{})
+		if err == nil || strings.Contains(err.Error(), e) == false {
+			t.Fatalf("Incorrect required option error for `%s`: %v", d, err)
+		}
+	}
+}
+
+func TestMapPairsDefault(t *testing.T) {
+
+	type tConfOut struct {
+		Weights map[string]int    `conf:"weights" conf_extraopts:"default=a=1;b=2"`
+		Labels  map[string]string `conf:"labels" conf_extraopts:"default=env=prod"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "labels:\n  team: core\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if len(c.Weights) != 2 || c.Weights["a"] != 1 || c.Weights["b"] != 2 {
+		t.Fatal("Incorrect loaded data: Weights")
+	}
+
+	// Check specified map is not merged with default value
+	if len(c.Labels) != 1 || c.Labels["team"] != "core" {
+		t.Fatal("Incorrect loaded data: Labels")
+	}
+
+	// Check invalid default value
+	type tConfInvalid struct {
+		Weights map[string]int `conf:"weights" conf_extraopts:"default=a=x"`
+	}
+
+	var ci tConfInvalid
+
+	if err := testLoadYAML(t, "{}\n", &ci, Settings{}); err == nil {
+		t.Fatal("Expected error for invalid default value")
+	}
+}
+
+func TestRootKey(t *testing.T) {
+
+	type tConfOut struct {
+		Listen  string `conf:"listen" conf_extraopts:"required"`
+		Workers int    `conf:"workers" conf_extraopts:"default=4"`
+	}
+
+	d := `
+services:
+  api:
+    listen: ":8080"
+  worker:
+    queue: jobs
+database:
+  host: localhost
+`
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, d, &c, Settings{
+		RootKey:     "services.api",
+		UnknownDeny: true,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Listen != ":8080" || c.Workers != 4 {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	// Check JSON
+	c = tConfOut{}
+
+	if err := LoadBytes(&c, []byte(`{"services": {"api": {"listen": ":9090"}}}`), Settings{
+		ConfType: ConfigTypeJSON,
+		RootKey:  "services.api",
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Listen != ":9090" {
+		t.Fatal("Incorrect loaded data: Listen")
+	}
+
+	// Check absent root key
+	if err := testLoadYAML(t, d, &c, Settings{
+		RootKey: "services.missing",
+	}); err == nil {
+		t.Fatal("Expected error for absent root key")
+	}
+
+	type tConfOptional struct {
+		Workers int `conf:"workers" conf_extraopts:"default=4"`
+	}
+
+	var co tConfOptional
+
+	if err := testLoadYAML(t, d, &co, Settings{
+		RootKey:         "services.missing",
+		RootKeyOptional: true,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if co.Workers != 4 {
+		t.Fatal("Incorrect loaded data: Workers")
+	}
+}
+
+func TestDeprecatedAlias(t *testing.T) {
+
+	type tConfOut struct {
+		DB struct {
+			Host string `conf:"host" conf_extraopts:"required,deprecated_alias=hostname"`
+		} `conf:"db"`
+	}
+
+	var (
+		c          tConfOut
+		deprecated [][2]string
+	)
+
+	onDeprecated := func(old, new string) {
+		deprecated = append(deprecated, [2]string{old, new})
+	}
+
+	// Check deprecated name populates the option
+	if err := testLoadYAML(t, "db:\n  hostname: old.example.com\n", &c, Settings{
+		UnknownDeny:  true,
+		OnDeprecated: onDeprecated,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.DB.Host != "old.example.com" {
+		t.Fatal("Incorrect loaded data: DB.Host")
+	}
+
+	if len(deprecated) != 1 || deprecated[0] != [2]string{"db.hostname", "db.host"} {
+		t.Fatal("Incorrect deprecated callback calls:", deprecated)
+	}
+
+	// Check actual name takes precedence
+	c = tConfOut{}
+	deprecated = nil
+
+	if err := testLoadYAML(t, "db:\n  hostname: old.example.com\n  host: new.example.com\n", &c, Settings{
+		UnknownDeny:  true,
+		OnDeprecated: onDeprecated,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.DB.Host != "new.example.com" {
+		t.Fatal("Incorrect loaded data: DB.Host")
+	}
+
+	if len(deprecated) != 1 {
+		t.Fatal("Incorrect deprecated callback calls:", deprecated)
+	}
+
+	// Check actual name is not reported
+	c = tConfOut{}
+	deprecated = nil
+
+	if err := testLoadYAML(t, "db:\n  host: new.example.com\n", &c, Settings{
+		OnDeprecated: onDeprecated,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if len(deprecated) != 0 {
+		t.Fatal("Incorrect deprecated callback calls:", deprecated)
+	}
+}
+
+func TestSanitizeStrings(t *testing.T) {
+
+	type tConfOut struct {
+		Host  string            `conf:"host"`
+		Mode  string            `conf:"mode"`
+		Tags  []string          `conf:"tags"`
+		Attrs map[string]string `conf:"attrs"`
+	}
+
+	d := "host: \"local\u200bhost\"\nmode: \" prod\ufeff \"\ntags: [\"a\u200d\"]\nattrs:\n  k: \"v\u2060\"\n"
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, d, &c, Settings{
+		SanitizeStrings: true,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Host != "localhost" {
+		t.Fatalf("Incorrect loaded data: Host: %q", c.Host)
+	}
+
+	if c.Mode != "prod" {
+		t.Fatalf("Incorrect loaded data: Mode: %q", c.Mode)
+	}
+
+	if c.Tags[0] != "a" || c.Attrs["k"] != "v" {
+		t.Fatal("Incorrect loaded data: Tags or Attrs")
+	}
+
+	// Check strings are kept as is without the setting
+	c = tConfOut{}
+
+	if err := testLoadYAML(t, d, &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Host != "local\u200bhost" {
+		t.Fatal("Incorrect loaded data: Host")
+	}
+}
+
+func TestBytesDecode(t *testing.T) {
+
+	type tConfOut struct {
+		SigningKey []byte `conf:"signing_key"`
+		FromEnv    []byte `conf:"from_env"`
+		HexKey     []byte `conf:"hex_key" conf_extraopts:"encoding=hex"`
+	}
+
+	os.Setenv("TEST_CONF_BYTES_KEY", "a2V5")
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "signing_key: c2VjcmV0\nfrom_env: ENV:TEST_CONF_BYTES_KEY\nhex_key: \"736563726574\"\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if string(c.SigningKey) != "secret" {
+		t.Fatal("Incorrect loaded data: SigningKey")
+	}
+
+	if string(c.FromEnv) != "key" {
+		t.Fatal("Incorrect loaded data: FromEnv")
+	}
+
+	if string(c.HexKey) != "secret" {
+		t.Fatal("Incorrect loaded data: HexKey")
+	}
+
+	err := testLoadYAML(t, "hex_key: zz\n", &tConfOut{}, Settings{})
+	if err == nil || strings.Contains(err.Error(), "hex_key") == false {
+		t.Fatal("Expected error for malformed hex value, got:", err)
+	}
+
+	err = testLoadYAML(t, "signing_key: \"%%%\"\n", &tConfOut{}, Settings{})
+	if err == nil || strings.Contains(err.Error(), "signing_key") == false {
+		t.Fatal("Expected error for malformed base64 value, got:", err)
+	}
+}
+
+func TestRegexpAny(t *testing.T) {
+
+	type tConfOut struct {
+		Env  string `conf:"env" conf_extraopts:"regexp_any=^prod-.*$|^stage-[0-9]{1\\,3}$"`
+		Name string `conf:"name" conf_extraopts:"regexp=^[a-z]+$"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "env: stage-12\nname: api\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if err := testLoadYAML(t, "env: prod-eu\nname: api\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if err := testLoadYAML(t, "env: stage-1234\nname: api\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected error for value matching no pattern")
+	}
+
+	if err := testLoadYAML(t, "env: dev\nname: api\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected error for value matching no pattern")
+	}
+
+	if err := testLoadYAML(t, "env: prod-eu\nname: API\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected error for value not matching pattern")
+	}
+}
+
+func TestSourcePath(t *testing.T) {
+
+	type tConfOut struct {
+		Host   string `conf:"host"`
+		Source string `conf:"-" conf_extraopts:"source_path"`
+	}
+
+	p := filepath.Join(t.TempDir(), "conf.yml")
+
+	if err := ioutil.WriteFile(p, []byte("host: localhost\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	var c tConfOut
+
+	if err := Load(&c, Settings{ConfPath: p, ConfType: ConfigTypeYAML}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Source != p {
+		t.Fatal("Incorrect loaded data: Source")
+	}
+
+	var b tConfOut
+
+	if err := LoadBytes(&b, []byte("host: localhost\n"), Settings{ConfType: ConfigTypeYAML}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if b.Source != "" {
+		t.Fatal("Incorrect loaded data: Source")
+	}
+}
+
+func TestAllocDefaults(t *testing.T) {
+
+	type tConfSub struct {
+		Timeout int    `conf:"timeout" conf_extraopts:"default=30"`
+		Mode    string `conf:"mode"`
+	}
+
+	type tConfNoDefaults struct {
+		Mode string `conf:"mode"`
+	}
+
+	type tConfOut struct {
+		Sub        *tConfSub        `conf:"sub" conf_extraopts:"alloc_defaults"`
+		Skipped    *tConfSub        `conf:"skipped"`
+		NoDefaults *tConfNoDefaults `conf:"no_defaults" conf_extraopts:"alloc_defaults"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "{}\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Sub == nil || c.Sub.Timeout != 30 {
+		t.Fatal("Incorrect loaded data: Sub")
+	}
+
+	if c.Skipped != nil {
+		t.Fatal("Incorrect loaded data: Skipped")
+	}
+
+	if c.NoDefaults != nil {
+		t.Fatal("Incorrect loaded data: NoDefaults")
+	}
+
+	var p tConfOut
+
+	if err := testLoadYAML(t, "sub:\n  mode: fast\n", &p, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if p.Sub == nil || p.Sub.Timeout != 30 || p.Sub.Mode != "fast" {
+		t.Fatal("Incorrect loaded data: Sub")
+	}
+}
+
+func TestAllocDefaultsRecursive(t *testing.T) {
+
+	type tConfNode struct {
+		Name string     `conf:"name" conf_extraopts:"default=node"`
+		Next *tConfNode `conf:"next" conf_extraopts:"alloc_defaults"`
+	}
+
+	type tConfOut struct {
+		Root *tConfNode `conf:"root" conf_extraopts:"alloc_defaults"`
+	}
+
+	// Self-referential struct is allocated once
+	var c tConfOut
+
+	if err := testLoadYAML(t, "{}\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Root == nil || c.Root.Name != "node" || c.Root.Next != nil {
+		t.Fatal("Incorrect loaded data: Root")
+	}
+
+	// Allocation starts over below specified options
+	var p tConfOut
+
+	if err := testLoadYAML(t, "root:\n  next:\n    name: second\n", &p, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if p.Root.Next.Name != "second" || p.Root.Next.Next == nil || p.Root.Next.Next.Name != "node" || p.Root.Next.Next.Next != nil {
+		t.Fatal("Incorrect loaded data: Root")
+	}
+}
+
+func TestDurationBounds(t *testing.T) {
+
+	type tConfOut struct {
+		Timeout time.Duration `conf:"timeout" conf_extraopts:"default=30s,min=10s,max=60s"`
+		Retry   time.Duration `conf:"retry" conf_extraopts:"default=5s,min=1s,max=10s,clamp"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "{}\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Timeout != 30*time.Second || c.Retry != 5*time.Second {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	if err := testLoadYAML(t, "timeout: 45s\nretry: 1m\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Timeout != 45*time.Second {
+		t.Fatal("Incorrect loaded data: Timeout")
+	}
+
+	if c.Retry != 10*time.Second {
+		t.Fatal("Incorrect loaded data: Retry must be clamped")
+	}
+
+	if err := testLoadYAML(t, "timeout: 2m\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected error for value above max")
+	}
+
+	type tConfBadDefault struct {
+		Timeout time.Duration `conf:"timeout" conf_extraopts:"default=5s,min=10s,clamp"`
+	}
+
+	if err := testLoadYAML(t, "{}\n", &tConfBadDefault{}, Settings{}); err == nil {
+		t.Fatal("Expected error for default value out of bounds")
+	}
+}
+
+func TestDurationStrictBounds(t *testing.T) {
+
+	type tConfOut struct {
+		Timeout time.Duration `conf:"timeout" conf_extraopts:"strict,min=1s,max=10s"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "timeout: 5s\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Timeout != 5*time.Second {
+		t.Fatal("Incorrect loaded data: Timeout")
+	}
+
+	if err := testLoadYAML(t, "timeout: 1m\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected error for value above max")
+	}
+
+	// Other strings are not converted for strict options
+	if err := testLoadYAML(t, "timeout: \"5\"\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected strict option error")
+	}
+}
+
+func TestDisableEnv(t *testing.T) {
+
+	type tConfOut struct {
+		Value string `conf:"value"`
+	}
+
+	os.Setenv("TEST_CONF_DISABLE_ENV", "expanded")
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "value: ENV:TEST_CONF_DISABLE_ENV\n", &c, Settings{DisableEnv: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Value != "ENV:TEST_CONF_DISABLE_ENV" {
+		t.Fatal("Incorrect loaded data: value must be literal")
+	}
+
+	if err := testLoadYAML(t, "value: ENV:TEST_CONF_DISABLE_ENV\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Value != "expanded" {
+		t.Fatal("Incorrect loaded data: value must be expanded")
+	}
+}
+
+func TestGroupAtLeast(t *testing.T) {
+
+	type tConfContacts struct {
+		Email string `conf:"email" conf_extraopts:"group=contacts,at_least=2"`
+		Phone string `conf:"phone" conf_extraopts:"group=contacts"`
+		Slack string `conf:"slack" conf_extraopts:"group=contacts"`
+	}
+
+	type tConfOut struct {
+		Owners []tConfContacts `conf:"owners"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "owners:\n  - email: a@example.com\n    slack: \"@a\"\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	err := testLoadYAML(t, "owners:\n  - email: a@example.com\n    slack: \"@a\"\n  - phone: \"123\"\n", &c, Settings{})
+	if err == nil || strings.Contains(err.Error(), "1 set") == false {
+		t.Fatal("Expected error for group with fewer options set, got:", err)
+	}
+}
+
+func TestNumbersUnits(t *testing.T) {
+
+	type tConfOut struct {
+		MaxItems int    `conf:"max_items"`
+		MaxBytes uint64 `conf:"max_bytes" conf_extraopts:"unit=bytes"`
+		Buffer   int    `conf:"buffer" conf_extraopts:"unit=bytes"`
+		Cache    int64  `conf:"cache" conf_extraopts:"unit=bytes,default=1GiB"`
+		Plain    int    `conf:"plain" conf_extraopts:"unit=bytes"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "max_items: \"10_000_000\"\nmax_bytes: \"10MB\"\nbuffer: 4 KiB\nplain: 512\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.MaxItems != 10000000 {
+		t.Fatal("Incorrect loaded data: MaxItems")
+	}
+
+	if c.MaxBytes != 10000000 {
+		t.Fatal("Incorrect loaded data: MaxBytes")
+	}
+
+	if c.Buffer != 4096 {
+		t.Fatal("Incorrect loaded data: Buffer")
+	}
+
+	if c.Cache != 1<<30 {
+		t.Fatal("Incorrect loaded data: Cache")
+	}
+
+	if c.Plain != 512 {
+		t.Fatal("Incorrect loaded data: Plain")
+	}
+
+	if err := testLoadYAML(t, "max_items: \"10MB\"\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected error for size value without unit extra option")
+	}
+}
+
+func TestCSV(t *testing.T) {
+
+	type tConfOut struct {
+		Hosts []string `conf:"hosts" conf_extraopts:"csv"`
+		Ports []int    `conf:"ports" conf_extraopts:"csv"`
+		Empty []string `conf:"empty" conf_extraopts:"csv"`
+		List  []string `conf:"list" conf_extraopts:"csv"`
+	}
+
+	os.Setenv("TEST_CONF_CSV_PORTS", "80, 443")
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "hosts: \"a, b,c\"\nports: ENV:TEST_CONF_CSV_PORTS\nempty: \"\"\nlist: [x, z]\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if reflect.DeepEqual(c.Hosts, []string{"a", "b", "c"}) == false {
+		t.Fatal("Incorrect loaded data: Hosts")
+	}
+
+	if reflect.DeepEqual(c.Ports, []int{80, 443}) == false {
+		t.Fatal("Incorrect loaded data: Ports")
+	}
+
+	if len(c.Empty) != 0 {
+		t.Fatal("Incorrect loaded data: Empty")
+	}
+
+	if reflect.DeepEqual(c.List, []string{"x", "z"}) == false {
+		t.Fatal("Incorrect loaded data: List")
+	}
+}
+
+func TestLoadWithMetaDefaulted(t *testing.T) {
+
+	type tConfOut struct {
+		IntTest int               `conf:"int_test" conf_extraopts:"default=5"`
+		StrTest string            `conf:"str_test" conf_extraopts:"default=str"`
+		IP      net.IP            `conf:"ip" conf_extraopts:"default=127.0.0.1"`
+		Labels  map[string]string `conf:"labels" conf_extraopts:"default=env=prod"`
+		Copy    string            `conf:"copy" conf_extraopts:"default_key=str_test"`
+	}
+
+	var c tConfOut
+
+	p := filepath.Join(t.TempDir(), "conf.yml")
+
+	if err := ioutil.WriteFile(p, []byte("str_test: value\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	m, err := LoadWithMeta(&c, Settings{ConfPath: p, ConfType: ConfigTypeYAML})
+	if err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if reflect.DeepEqual(m.Defaulted, []string{"int_test", "ip", "labels", "copy"}) == false {
+		t.Fatal("Incorrect defaulted options:", m.Defaulted)
+	}
+}
+
+func TestLoadWithMetaSources(t *testing.T) {
+
+	type tConfOut struct {
+		Password string `conf:"password"`
+		User     string `conf:"user"`
+		Port     int    `conf:"port" conf_extraopts:"default=5432"`
+	}
+
+	var c tConfOut
+
+	os.Setenv("DB_PASSWORD", "secret")
+	defer os.Unsetenv("DB_PASSWORD")
+
+	p := filepath.Join(t.TempDir(), "conf.yml")
+
+	if err := ioutil.WriteFile(p, []byte("password: ENV:DB_PASSWORD\nuser: admin\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	m, err := LoadWithMeta(&c, Settings{ConfPath: p, ConfType: ConfigTypeYAML})
+	if err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Password != "secret" {
+		t.Fatal("Incorrect loaded data: Password")
+	}
+
+	s := map[string]string{
+		"password": SourceEnv,
+		"user":     SourceLiteral,
+		"port":     SourceDefault,
+	}
+
+	if reflect.DeepEqual(m.Sources, s) == false {
+		t.Fatal("Incorrect options sources:", m.Sources)
+	}
+}
+
+func TestSliceRequired(t *testing.T) {
+
+	type tConfBackend struct {
+		Host string `conf:"host" conf_extraopts:"required"`
+		Port int    `conf:"port"`
+	}
+
+	type tConfOut struct {
+		Backends []tConfBackend  `conf:"backends"`
+		Pointers []*tConfBackend `conf:"pointers"`
+	}
+
+	d := "backends:\n  - host: a\n  - port: 80\n"
+
+	for _, s := range []Settings{{}, {RequiredCheckFirst: true}} {
+		err := testLoadYAML(t, d, &tConfOut{}, s)
+		if err == nil || strings.Contains(err.Error(), "required option 'backends[1].host'") == false {
+			t.Fatal("Incorrect required option error:", err)
+		}
+	}
+
+	err := testLoadYAML(t, "pointers:\n  - host: a\n  - port: 80\n", &tConfOut{}, Settings{})
+	if err == nil || strings.Contains(err.Error(), "required option 'pointers[1].host'") == false {
+		t.Fatal("Incorrect required option error:", err)
+	}
+
+	if err := testLoadYAML(t, "backends:\n  - host: a\n  - host: b\n", &tConfOut{}, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+}
+
+func TestValidateEnvUpfront(t *testing.T) {
+
+	type tConfOut struct {
+		User  string   `conf:"user"`
+		Pass  string   `conf:"pass"`
+		Hosts []string `conf:"hosts"`
+	}
+
+	os.Unsetenv("TEST_CONF_UPFRONT_USER")
+	os.Unsetenv("TEST_CONF_UPFRONT_HOST")
+	os.Setenv("TEST_CONF_UPFRONT_PASS", "pass")
+
+	d := "user: ENV:TEST_CONF_UPFRONT_USER\npass: ENV:TEST_CONF_UPFRONT_PASS\nhosts: [ENV:TEST_CONF_UPFRONT_HOST]\n"
+
+	err := testLoadYAML(t, d, &tConfOut{}, Settings{ValidateEnvUpfront: true})
+	if err == nil || strings.Contains(err.Error(), "TEST_CONF_UPFRONT_HOST, TEST_CONF_UPFRONT_USER") == false {
+		t.Fatal("Expected error with all empty ENV variables, got:", err)
+	}
+
+	os.Setenv("TEST_CONF_UPFRONT_USER", "user")
+	os.Setenv("TEST_CONF_UPFRONT_HOST", "host")
+
+	if err := testLoadYAML(t, d, &tConfOut{}, Settings{ValidateEnvUpfront: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+}
+
+func TestSquash(t *testing.T) {
+
+	type tConfCommon struct {
+		Name    string `conf:"name" conf_extraopts:"required"`
+		Timeout int    `conf:"timeout" conf_extraopts:"default=30"`
+	}
+
+	type tConfServer struct {
+		Common tConfCommon `conf:",squash"`
+		Port   int         `conf:"port"`
+	}
+
+	type tConfOut struct {
+		Server tConfServer `conf:"server"`
+	}
+
+	var c tConfOut
+
+	for _, s := range []Settings{{UnknownDeny: true}, {UnknownDeny: true, RequiredCheckFirst: true}} {
+
+		c = tConfOut{}
+
+		if err := testLoadYAML(t, "server:\n  name: api\n  port: 80\n", &c, s); err != nil {
+			t.Fatal("Config load error:", err)
+		}
+
+		if c.Server.Common.Name != "api" || c.Server.Port != 80 || c.Server.Common.Timeout != 30 {
+			t.Fatal("Incorrect loaded data")
+		}
+
+		err := testLoadYAML(t, "server:\n  port: 80\n", &c, s)
+		if err == nil || strings.Contains(err.Error(), "required option 'server.name'") == false {
+			t.Fatal("Incorrect required option error:", err)
+		}
+	}
+
+	c = tConfOut{}
+
+	if err := testLoadYAML(t, "server:\n  name: api\n  timeout: 5\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Server.Common.Timeout != 5 {
+		t.Fatal("Incorrect loaded data: Timeout")
+	}
+}
+
+func TestDecoderConfig(t *testing.T) {
+
+	type tConfOut struct {
+		Labels map[string]string `conf:"labels"`
+	}
+
+	c := tConfOut{Labels: map[string]string{"old": "value"}}
+
+	if err := testLoadYAML(t, "labels:\n  new: value\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if len(c.Labels) != 2 {
+		t.Fatal("Incorrect loaded data: map must be merged")
+	}
+
+	c = tConfOut{Labels: map[string]string{"old": "value"}}
+
+	if err := testLoadYAML(t, "labels:\n  new: value\n", &c, Settings{
+		DecoderConfig: func(c *mapstructure.DecoderConfig) {
+			c.ZeroFields = true
+		},
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if len(c.Labels) != 1 || c.Labels["new"] != "value" {
+		t.Fatal("Incorrect loaded data: map must be replaced")
+	}
+}
+
+func TestLoadStdin(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name" conf_extraopts:"required"`
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal("Pipe create error:", err)
+	}
+
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() {
+		os.Stdin = stdin
+	}()
+
+	if _, err := w.Write([]byte("name: test\n")); err != nil {
+		t.Fatal("Pipe write error:", err)
+	}
+	w.Close()
+
+	var c tConfOut
+
+	if err := Load(&c, Settings{ConfPath: "-", ConfType: ConfigTypeYAML}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "test" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+}
+
+func TestEnvOverride(t *testing.T) {
+
+	type tConfOut struct {
+		DB struct {
+			Host     string `conf:"host"`
+			Password string `conf:"password" conf_extraopts:"required,env=TEST_CONF_PGPASSWORD"`
+			Port     int    `conf:"port" conf_extraopts:"env=TEST_CONF_PGPORT"`
+		} `conf:"db"`
+	}
+
+	os.Setenv("TEST_CONF_PGPASSWORD", "secret")
+	os.Unsetenv("TEST_CONF_PGPORT")
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "db:\n  host: localhost\n  password: file\n  port: 5432\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.DB.Password != "secret" {
+		t.Fatal("Incorrect loaded data: Password must be overridden")
+	}
+
+	if c.DB.Port != 5432 {
+		t.Fatal("Incorrect loaded data: Port must keep file value")
+	}
+
+	os.Setenv("TEST_CONF_PGPORT", "6432")
+
+	c = tConfOut{}
+
+	// Options absent in config file are set as well
+	if err := testLoadYAML(t, "{}\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.DB.Password != "secret" || c.DB.Port != 6432 {
+		t.Fatal("Incorrect loaded data")
+	}
+}
+
+func TestLenBounds(t *testing.T) {
+
+	type tConfOut struct {
+		Tags  []string `conf:"tags" conf_extraopts:"minlen=1,maxlen=3"`
+		Name  string   `conf:"name" conf_extraopts:"minlen=2,maxlen=5"`
+		Empty []string `conf:"empty" conf_extraopts:"maxlen=0"`
+	}
+
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"tags: [a]\nname: ab\n", ""},
+		{"tags: [a, b, c]\nname: абвгд\n", ""},
+		{"tags: []\nname: ab\n", "option 'tags' length 0 is less than minimum 1"},
+		{"tags: [a, b, c, d]\nname: ab\n", "option 'tags' length 4 is greater than maximum 3"},
+		{"tags: [a]\nname: a\n", "option 'name' length 1 is less than minimum 2"},
+		{"tags: [a]\nname: abcdef\n", "option 'name' length 6 is greater than maximum 5"},
+		{"tags: [a]\nname: ab\nempty: [a]\n", "option 'empty' length 1 is greater than maximum 0"},
+	}
+
+	for _, tt := range tests {
+		err := testLoadYAML(t, tt.data, &tConfOut{}, Settings{})
+		if tt.err == "" && err != nil {
+			t.Fatalf("Config load error for `%s`: %v", tt.data, err)
+		}
+		if tt.err != "" && (err == nil || strings.Contains(err.Error(), tt.err) == false) {
+			t.Fatalf("Incorrect length error for `%s`: %v", tt.data, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name" conf_extraopts:"required"`
+		Port int    `conf:"port" conf_extraopts:"default=80,min=1,max=65535"`
+	}
+
+	p := filepath.Join(t.TempDir(), "conf.yml")
+
+	for d, ok := range map[string]bool{
+		"name: app\n":              true,
+		"port: 8080\n":             false,
+		"name: app\nport: 70000\n": false,
+		"name: app\nextra: 1\n":    false,
+	} {
+
+		if err := ioutil.WriteFile(p, []byte(d), 0644); err != nil {
+			t.Fatal("Config file prepare error:", err)
+		}
+
+		err := Validate(tConfOut{}, Settings{ConfPath: p, ConfType: ConfigTypeYAML, UnknownDeny: true})
+		if ok == true && err != nil {
+			t.Fatalf("Unexpected validation error for `%s`: %v", d, err)
+		}
+		if ok == false && err == nil {
+			t.Fatalf("Expected validation error for `%s`", d)
+		}
+	}
+}
+
+func TestStringsNormalize(t *testing.T) {
+
+	type tConfOut struct {
+		Mode    string `conf:"mode" conf_extraopts:"trim,lower,regexp=^(prod|dev)$"`
+		Trimmed string `conf:"trimmed" conf_extraopts:"trim"`
+		Lower   string `conf:"lower" conf_extraopts:"lower"`
+		Upper   string `conf:"upper" conf_extraopts:"upper"`
+		Env     string `conf:"env" conf_extraopts:"trim,upper"`
+	}
+
+	os.Setenv("TEST_CONF_NORMALIZE", " eu-west ")
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "mode: \"  PROD \"\ntrimmed: \" a B \"\nlower: \" A b \"\nupper: \" a B \"\nenv: ENV:TEST_CONF_NORMALIZE\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Mode != "prod" {
+		t.Fatal("Incorrect loaded data: Mode")
+	}
+
+	if c.Trimmed != "a B" {
+		t.Fatal("Incorrect loaded data: Trimmed")
+	}
+
+	if c.Lower != " a b " {
+		t.Fatal("Incorrect loaded data: Lower")
+	}
+
+	if c.Upper != " A B " {
+		t.Fatal("Incorrect loaded data: Upper")
+	}
+
+	if c.Env != "EU-WEST" {
+		t.Fatal("Incorrect loaded data: Env")
+	}
+}
+
+func TestDefaultWithCommas(t *testing.T) {
+
+	type tConfOut struct {
+		Quoted  string            `conf:"quoted" conf_extraopts:"default='a,b,c',trim"`
+		Escaped string            `conf:"escaped" conf_extraopts:"default=a\\,b"`
+		Labels  map[string]string `conf:"labels" conf_extraopts:"default='env=prod,dev;team=core'"`
+		Quote   string            `conf:"quote" conf_extraopts:"default=it's,desc=Quote within value"`
+		Regexp  string            `conf:"regexp" conf_extraopts:"regexp='^[a-z]{1,3}$'"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "regexp: abc\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Quoted != "a,b,c" {
+		t.Fatal("Incorrect loaded data: Quoted")
+	}
+
+	if c.Escaped != "a,b" {
+		t.Fatal("Incorrect loaded data: Escaped")
+	}
+
+	if c.Labels["env"] != "prod,dev" || c.Labels["team"] != "core" {
+		t.Fatal("Incorrect loaded data: Labels")
+	}
+
+	if c.Quote != "it's" {
+		t.Fatal("Incorrect loaded data: Quote")
+	}
+
+	if err := testLoadYAML(t, "regexp: abcd\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected error for value not matching pattern")
+	}
+}
+
+func TestGzip(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name" conf_extraopts:"required"`
+	}
+
+	var b bytes.Buffer
+
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write([]byte("name: compressed\n")); err != nil {
+		t.Fatal("Gzip write error:", err)
+	}
+	w.Close()
+
+	p := filepath.Join(t.TempDir(), "conf.yml.gz")
+
+	if err := ioutil.WriteFile(p, b.Bytes(), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	var c tConfOut
+
+	if err := Load(&c, Settings{ConfPath: p, ConfType: ConfigTypeAuto}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "compressed" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	if err := LoadBytes(&c, b.Bytes(), Settings{ConfType: ConfigTypeYAML}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if err := LoadBytes(&c, []byte("name: plain\n"), Settings{ConfType: ConfigTypeYAML}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "plain" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	if err := LoadBytes(&c, b.Bytes()[:12], Settings{ConfType: ConfigTypeYAML}); err == nil {
+		t.Fatal("Expected error for truncated gzip data")
+	}
+}
+
+func TestDurationUnit(t *testing.T) {
+
+	type tConfOut struct {
+		Timeout  time.Duration `conf:"timeout" conf_extraopts:"unit=s"`
+		Interval time.Duration `conf:"interval" conf_extraopts:"unit=ms"`
+		Delay    time.Duration `conf:"delay" conf_extraopts:"unit=s"`
+		Retry    time.Duration `conf:"retry" conf_extraopts:"unit=s,default=5"`
+		Period   time.Duration `conf:"period" conf_extraopts:"unit=m"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "timeout: 30\ninterval: 1.5\ndelay: \"30s\"\nperiod: \"2\"\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Timeout != 30*time.Second {
+		t.Fatal("Incorrect loaded data: Timeout")
+	}
+
+	if c.Interval != 1500*time.Microsecond {
+		t.Fatal("Incorrect loaded data: Interval")
+	}
+
+	if c.Delay != 30*time.Second {
+		t.Fatal("Incorrect loaded data: Delay")
+	}
+
+	if c.Retry != 5*time.Second {
+		t.Fatal("Incorrect loaded data: Retry")
+	}
+
+	if c.Period != 2*time.Minute {
+		t.Fatal("Incorrect loaded data: Period")
+	}
+}
+
+func TestEnvVarPrefix(t *testing.T) {
+
+	type tConfOut struct {
+		Host string `conf:"host"`
+		Home string `conf:"home"`
+	}
+
+	os.Setenv("TEST_CONF_DB_HOST", "db.local")
+	os.Setenv("TEST_CONF_HOME", "/home/test")
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "host: ENV:HOST\nhome: ENV:!TEST_CONF_HOME\n", &c, Settings{EnvVarPrefix: "TEST_CONF_DB_"}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Host != "db.local" {
+		t.Fatal("Incorrect loaded data: Host")
+	}
+
+	if c.Home != "/home/test" {
+		t.Fatal("Incorrect loaded data: Home")
+	}
+}
+
+func TestTopLevelMap(t *testing.T) {
+
+	type tService struct {
+		Host string `conf:"host" conf_extraopts:"required"`
+		Port int    `conf:"port" conf_extraopts:"default=80"`
+	}
+
+	var c map[string]tService
+
+	if err := testLoadYAML(t, "web:\n  host: web.local\napi:\n  host: api.local\n  port: 8080\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c["web"].Host != "web.local" || c["web"].Port != 80 {
+		t.Fatal("Incorrect loaded data: web")
+	}
+
+	if c["api"].Host != "api.local" || c["api"].Port != 8080 {
+		t.Fatal("Incorrect loaded data: api")
+	}
+
+	var p map[string]*tService
+
+	if err := testLoadYAML(t, "web:\n  host: web.local\n", &p, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if p["web"] == nil || p["web"].Port != 80 {
+		t.Fatal("Incorrect loaded data: pointer web")
+	}
+
+	var r map[string]tService
+
+	err := testLoadYAML(t, "web:\n  host: web.local\napi:\n  port: 8080\n", &r, Settings{})
+	if err == nil {
+		t.Fatal("Expected required option error")
+	}
+
+	if strings.Contains(err.Error(), "'[api].host'") == false {
+		t.Fatal("Incorrect error:", err)
+	}
+}
+
+func TestRemain(t *testing.T) {
+
+	type tPlugin struct {
+		Name    string                 `conf:"name" conf_extraopts:"required"`
+		Enabled bool                   `conf:"enabled"`
+		Options map[string]interface{} `conf:",remain"`
+	}
+
+	type tConfOut struct {
+		Name    string    `conf:"name"`
+		Plugins []tPlugin `conf:"plugins"`
+	}
+
+	d := `
+name: test
+plugins:
+  - name: cache
+    enabled: true
+    size: 100
+    backend:
+      host: localhost
+  - name: log
+`
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, d, &c, Settings{UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Plugins[0].Name != "cache" || c.Plugins[0].Enabled != true {
+		t.Fatal("Incorrect loaded data: Plugins[0]")
+	}
+
+	if len(c.Plugins[0].Options) != 2 || c.Plugins[0].Options["size"] != 100 {
+		t.Fatal("Incorrect loaded data: Plugins[0].Options:", c.Plugins[0].Options)
+	}
+
+	if _, ok := c.Plugins[0].Options["backend"]; ok == false {
+		t.Fatal("Incorrect loaded data: Plugins[0].Options: backend")
+	}
+
+	if len(c.Plugins[1].Options) != 0 {
+		t.Fatal("Incorrect loaded data: Plugins[1].Options")
+	}
+
+	// Remain fields are not options themselves
+	type tConfPlugin struct {
+		Plugin tPlugin `conf:"plugin"`
+	}
+
+	if k := Keys(&tConfPlugin{}); reflect.DeepEqual(k, []string{"plugin", "plugin.name", "plugin.enabled"}) == false {
+		t.Fatal("Incorrect keys:", k)
+	}
+
+	for _, d := range Describe(&c) {
+		if strings.Contains(d.Path, "Options") == true {
+			t.Fatal("Incorrect description of remain field:", d.Path)
+		}
+	}
+
+	// Collected options are flattened as options of the struct
+	f := Flatten(&c)
+
+	if f["plugins[0].size"] != "100" {
+		t.Fatal("Incorrect flattened config:", f)
+	}
+
+	for k := range f {
+		if strings.Contains(k, "Options") == true {
+			t.Fatal("Incorrect flattened config:", f)
+		}
+	}
+
+	// Unknown options with null values are collected as nil
+	var n tConfOut
+
+	if err := testLoadYAML(t, "plugins:\n  - name: cache\n    size: null\n", &n, Settings{UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if v, ok := n.Plugins[0].Options["size"]; ok == false || v != nil {
+		t.Fatal("Incorrect loaded data: Plugins[0].Options:", n.Plugins[0].Options)
+	}
+
+	// Unknown options are still denied outside of structs with `remain` field
+	var u tConfOut
+
+	err := testLoadYAML(t, "name: test\nport: 80\n", &u, Settings{UnknownDeny: true})
+	if err == nil {
+		t.Fatal("Expected unknown option error")
+	}
+
+	if strings.Contains(err.Error(), "'port'") == false {
+		t.Fatal("Incorrect error:", err)
+	}
+}
+
+func TestRequiredWithDefault(t *testing.T) {
+
+	type tConfOut struct {
+		Name     string `conf:"name" conf_extraopts:"required"`
+		Port     int    `conf:"port" conf_extraopts:"required,default=80"`
+		Database struct {
+			Host string `conf:"host" conf_extraopts:"required,default=localhost"`
+		} `conf:"database" conf_extraopts:"required"`
+	}
+
+	var c tConfOut
+
+	// Default value satisfies the requirement
+	for _, f := range []bool{false, true} {
+		if err := testLoadYAML(t, "name: test\ndatabase: {}\n", &c, Settings{RequiredCheckFirst: f}); err != nil {
+			t.Fatal("Config load error:", err)
+		}
+
+		if c.Port != 80 || c.Database.Host != "localhost" {
+			t.Fatal("Incorrect loaded data: defaults")
+		}
+	}
+
+	// Specified value is used
+	var p tConfOut
+
+	if err := testLoadYAML(t, "name: test\nport: 8080\ndatabase:\n  host: db.local\n", &p, Settings{RequiredDefaultDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if p.Port != 8080 || p.Database.Host != "db.local" {
+		t.Fatal("Incorrect loaded data: specified values")
+	}
+
+	// Default value doesn't satisfy the requirement with `RequiredDefaultDeny`
+	for _, f := range []bool{false, true} {
+
+		var d tConfOut
+
+		err := testLoadYAML(t, "name: test\nport: 8080\ndatabase: {}\n", &d, Settings{RequiredDefaultDeny: true, RequiredCheckFirst: f})
+		if err == nil {
+			t.Fatal("Expected required option error")
+		}
+
+		if strings.Contains(err.Error(), "'database.host'") == false {
+			t.Fatal("Incorrect error:", err)
+		}
+	}
+
+	// Required option without default is still checked
+	var r tConfOut
+
+	if err := testLoadYAML(t, "database: {}\n", &r, Settings{}); err == nil {
+		t.Fatal("Expected required option error")
+	}
+}
+
+func TestLoadContext(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name"`
+	}
+
+	p := filepath.Join(t.TempDir(), "conf.yml")
+	if err := ioutil.WriteFile(p, []byte("name: test\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	var c tConfOut
+
+	if err := LoadContext(context.Background(), &c, Settings{ConfPath: p}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "test" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var d tConfOut
+
+	err := LoadContext(ctx, &d, Settings{ConfPath: p})
+	if errors.Is(err, context.Canceled) == false {
+		t.Fatal("Expected context error, got:", err)
+	}
+
+	if d.Name != "" {
+		t.Fatal("Config is loaded with cancelled context")
+	}
+}
+
+func TestAliases(t *testing.T) {
+
+	type tConfOut struct {
+		Host string `conf:"host" conf_extraopts:"aliases=hostname server_host"`
+		Port int    `conf:"port" conf_extraopts:"required,aliases=server_port"`
+	}
+
+	p := filepath.Join(t.TempDir(), "conf.yml")
+	if err := ioutil.WriteFile(p, []byte("server_host: example.com\nserver_port: 8080\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	var c tConfOut
+
+	w, err := LoadWithWarnings(&c, Settings{
+		ConfPath:    p,
+		ConfType:    ConfigTypeYAML,
+		UnknownDeny: true,
+	})
+	if err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Host != "example.com" || c.Port != 8080 {
+		t.Fatal("Incorrect loaded data: aliases")
+	}
+
+	if len(w) != 0 {
+		t.Fatal("Unexpected warnings:", w)
+	}
+
+	// Actual name and the first alias take precedence
+	var b tConfOut
+
+	if err := testLoadYAML(t, "host: example.com\nhostname: example.org\nport: 80\nserver_port: 8080\n", &b, Settings{UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if b.Host != "example.com" || b.Port != 80 {
+		t.Fatal("Incorrect loaded data: primary names")
+	}
+
+	var a tConfOut
+
+	if err := testLoadYAML(t, "hostname: example.org\nserver_host: example.com\nport: 80\n", &a, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if a.Host != "example.org" {
+		t.Fatal("Incorrect loaded data: first alias")
+	}
+}
+
+func TestPreDecode(t *testing.T) {
+
+	type tConfOut struct {
+		Name     string `conf:"name" conf_extraopts:"required"`
+		Database struct {
+			Host string `conf:"host" conf_extraopts:"required"`
+		} `conf:"database"`
+	}
+
+	// Migrate flat `db_host` option into `database` section
+	migrate := func(raw map[string]interface{}) (map[string]interface{}, error) {
+		if h, ok := raw["db_host"]; ok == true {
+			raw["database"] = map[string]interface{}{"host": h}
+			delete(raw, "db_host")
+		}
+		raw["name"] = "injected"
+		return raw, nil
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "db_host: localhost\n", &c, Settings{PreDecode: migrate, UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "injected" || c.Database.Host != "localhost" {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	// Check error aborts loading
+	err := testLoadYAML(t, "name: test\n", &c, Settings{PreDecode: func(raw map[string]interface{}) (map[string]interface{}, error) {
+		return nil, fmt.Errorf("migration failed")
+	}})
+	if err == nil || strings.Contains(err.Error(), "migration failed") == false {
+		t.Fatal("Expected PreDecode error, got:", err)
+	}
+}
+
+func TestStringify(t *testing.T) {
+
+	type tConfOut struct {
+		Build   string `conf:"build" conf_extraopts:"stringify"`
+		Version string `conf:"version" conf_extraopts:"stringify"`
+		Release string `conf:"release" conf_extraopts:"stringify"`
+		Name    string `conf:"name"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "build: 42\nversion: 1.0\nrelease: 2.50\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Build != "42" || c.Version != "1" || c.Release != "2.5" {
+		t.Fatal("Incorrect loaded data:", c)
+	}
+
+	// Options without the extra option are not coerced
+	if err := testLoadYAML(t, "name: 1.0\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected decode error for number in string option")
+	}
+}
+
+func TestRequiredUnless(t *testing.T) {
+
+	type tConfOut struct {
+		Mode     string `conf:"mode" conf_extraopts:"default=prod"`
+		Password string `conf:"password" conf_extraopts:"required_unless=mode test"`
+	}
+
+	var c tConfOut
+
+	// Sibling matches, so option is optional
+	if err := testLoadYAML(t, "mode: test\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	// Sibling doesn't match (default value is used), so option is required
+	err := testLoadYAML(t, "{}\n", &c, Settings{})
+	if err == nil || strings.Contains(err.Error(), "required option 'password' is not specified") == false {
+		t.Fatal("Incorrect required option error:", err)
+	}
+
+	if err := testLoadYAML(t, "password: secret\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+}
+
+func TestRequiredPointer(t *testing.T) {
+
+	type tConfTLS struct {
+		Cert string `conf:"cert" conf_extraopts:"required"`
+		Key  string `conf:"key" conf_extraopts:"required"`
+		Port int    `conf:"port" conf_extraopts:"default=443"`
+	}
+
+	type tConfOut struct {
+		TLS   *tConfTLS `conf:"tls"`
+		Alloc *tConfTLS `conf:"alloc" conf_extraopts:"alloc_defaults"`
+	}
+
+	type tConfRequired struct {
+		TLS *tConfTLS `conf:"tls" conf_extraopts:"required"`
+	}
+
+	// Absent block is skipped
+	var a tConfOut
+
+	if err := testLoadYAML(t, "{}\n", &a, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if a.TLS != nil || a.Alloc == nil || a.Alloc.Port != 443 {
+		t.Fatal("Incorrect loaded data: absent blocks")
+	}
+
+	// Present and complete block
+	var c tConfOut
+
+	if err := testLoadYAML(t, "tls:\n  cert: a.crt\n  key: a.key\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.TLS == nil || c.TLS.Cert != "a.crt" || c.TLS.Port != 443 {
+		t.Fatal("Incorrect loaded data: present block")
+	}
+
+	// Present but incomplete block
+	for _, d := range []string{"tls:\n  cert: a.crt\n", "alloc:\n  cert: a.crt\n"} {
+		for _, s := range []Settings{{}, {RequiredCheckFirst: true}} {
+			err := testLoadYAML(t, d, &tConfOut{}, s)
+			if err == nil || strings.Contains(err.Error(), "required option") == false || strings.Contains(err.Error(), ".key'") == false {
+				t.Fatal("Incorrect required option error:", err)
+			}
+		}
+	}
+
+	// Required block must be present
+	err := testLoadYAML(t, "{}\n", &tConfRequired{}, Settings{})
+	if err == nil || strings.Contains(err.Error(), "required option 'tls' is not specified") == false {
+		t.Fatal("Incorrect required option error:", err)
+	}
+}
+
+func TestBoolWords(t *testing.T) {
+
+	type tConfOut struct {
+		Enabled bool `conf:"enabled"`
+		Debug   bool `conf:"debug" conf_extraopts:"default=on"`
+		Verbose bool `conf:"verbose"`
+	}
+
+	var c tConfOut
+
+	os.Setenv("TEST_VERBOSE", "OFF")
+	defer os.Unsetenv("TEST_VERBOSE")
+
+	if err := testLoadYAML(t, "enabled: \"yes\"\nverbose: ENV:TEST_VERBOSE\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Enabled != true || c.Debug != true || c.Verbose != false {
+		t.Fatal("Incorrect loaded data:", c)
+	}
+
+	err := testLoadYAML(t, "enabled: \"maybe\"\n", &tConfOut{}, Settings{})
+	if err == nil || strings.Contains(err.Error(), "maybe") == false {
+		t.Fatal("Expected bool parse error, got:", err)
+	}
+}
+
+func TestMapValuesDefaults(t *testing.T) {
+
+	type tConfBackend struct {
+		Host    string `conf:"host" conf_extraopts:"required"`
+		Port    int    `conf:"port" conf_extraopts:"default=80"`
+		Weight  int    `conf:"weight" conf_extraopts:"default=1"`
+		Options struct {
+			Timeout time.Duration `conf:"timeout" conf_extraopts:"default=5s"`
+		} `conf:"options"`
+	}
+
+	type tConfOut struct {
+		Backends map[string]tConfBackend  `conf:"backends"`
+		Pointers map[string]*tConfBackend `conf:"pointers"`
+		Limits   map[string]int           `conf:"limits" conf_extraopts:"default=a=1;b=2"`
+	}
+
+	var c tConfOut
+
+	d := "backends:\n  a:\n    host: a.local\n  b:\n    host: b.local\n    port: 8080\n    options:\n      timeout: 1s\npointers:\n  c:\n    host: c.local\nlimits:\n  a: 10\n"
+
+	if err := testLoadYAML(t, d, &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Backends["a"].Port != 80 || c.Backends["a"].Weight != 1 || c.Backends["a"].Options.Timeout != 5*time.Second {
+		t.Fatal("Incorrect loaded data: Backends[a]")
+	}
+
+	if c.Backends["b"].Port != 8080 || c.Backends["b"].Weight != 1 || c.Backends["b"].Options.Timeout != time.Second {
+		t.Fatal("Incorrect loaded data: Backends[b]")
+	}
+
+	if c.Pointers["c"] == nil || c.Pointers["c"].Port != 80 || c.Pointers["c"].Options.Timeout != 5*time.Second {
+		t.Fatal("Incorrect loaded data: Pointers[c]")
+	}
+
+	// Scalar-value maps have no per-key defaults
+	if reflect.DeepEqual(c.Limits, map[string]int{"a": 10}) == false {
+		t.Fatal("Incorrect loaded data: Limits", c.Limits)
+	}
+}
+
+func TestRequiredEmpty(t *testing.T) {
+
+	type tConfOut struct {
+		User     string `conf:"user" conf_extraopts:"required"`
+		Password string `conf:"password" conf_extraopts:"required"`
+		Token    string `conf:"token" conf_extraopts:"required,env=TEST_REQUIRED_TOKEN"`
+		Comment  string `conf:"comment"`
+	}
+
+	os.Setenv("TEST_REQUIRED_TOKEN", "")
+	defer os.Unsetenv("TEST_REQUIRED_TOKEN")
+
+	// Empty string specified directly
+	for _, s := range []Settings{{}, {RequiredCheckFirst: true}} {
+		err := testLoadYAML(t, "user: \"\"\npassword: secret\ncomment: \"\"\n", &tConfOut{}, s)
+		if err == nil || strings.Contains(err.Error(), "required option 'user' is empty") == false {
+			t.Fatal("Incorrect required option error:", err)
+		}
+	}
+
+	// Empty ENV variable referenced in config file
+	err := testLoadYAML(t, "user: admin\npassword: ENV:TEST_REQUIRED_EMPTY\n", &tConfOut{}, Settings{})
+	if err == nil || strings.Contains(err.Error(), "error decoding 'password': empty ENV variable 'TEST_REQUIRED_EMPTY'") == false {
+		t.Fatal("Incorrect empty ENV variable error:", err)
+	}
+
+	// Empty ENV variable specified by extra option
+	err = testLoadYAML(t, "user: admin\npassword: secret\ntoken: abc\n", &tConfOut{}, Settings{})
+	if err == nil || strings.Contains(err.Error(), "required option 'token' is empty") == false {
+		t.Fatal("Incorrect required option error:", err)
+	}
+}
+
+func TestNullClearsDefault(t *testing.T) {
+
+	type tConfSub struct {
+		Name string `conf:"name" conf_extraopts:"default=sub"`
+	}
+
+	type tConfOut struct {
+		IntTest  int       `conf:"int_test" conf_extraopts:"default=5"`
+		StrTest  string    `conf:"str_test" conf_extraopts:"default=str"`
+		Copy     string    `conf:"copy" conf_extraopts:"default_key=str_test"`
+		Sub      *tConfSub `conf:"sub" conf_extraopts:"alloc_defaults"`
+		Required int       `conf:"required" conf_extraopts:"required"`
+	}
+
+	// Explicit null keeps options zero
+	var n tConfOut
+
+	p := filepath.Join(t.TempDir(), "conf.yml")
+	if err := ioutil.WriteFile(p, []byte("int_test: null\nstr_test: ~\ncopy: null\nsub: null\nrequired: 1\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	w, err := LoadWithWarnings(&n, Settings{ConfPath: p, ConfType: ConfigTypeYAML})
+	if err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if n.IntTest != 0 || n.StrTest != "" || n.Copy != "" || n.Sub != nil {
+		t.Fatal("Incorrect loaded data: null values", n)
+	}
+
+	if len(w) != 0 {
+		t.Fatal("Unexpected warnings:", w)
+	}
+
+	// Omitted keys get defaults
+	var o tConfOut
+
+	if err := testLoadYAML(t, "required: 1\n", &o, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if o.IntTest != 5 || o.StrTest != "str" || o.Copy != "str" || o.Sub == nil || o.Sub.Name != "sub" {
+		t.Fatal("Incorrect loaded data: omitted values", o)
+	}
+
+	// Real values are kept
+	var v tConfOut
+
+	if err := testLoadYAML(t, "int_test: 7\nstr_test: val\nrequired: 1\n", &v, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if v.IntTest != 7 || v.StrTest != "val" || v.Copy != "val" {
+		t.Fatal("Incorrect loaded data: real values", v)
+	}
+
+	// Null doesn't satisfy requirement
+	err = testLoadYAML(t, "required: null\n", &tConfOut{}, Settings{})
+	if err == nil || strings.Contains(err.Error(), "required option 'required' is not specified") == false {
+		t.Fatal("Incorrect required option error:", err)
+	}
+}
+
+func TestIntegerLiterals(t *testing.T) {
+
+	type tConfOut struct {
+		Hex    int   `conf:"hex" conf_extraopts:"default=0xFF"`
+		Octal  int64 `conf:"octal" conf_extraopts:"default=0o17"`
+		Binary uint8 `conf:"binary" conf_extraopts:"default=0b101"`
+	}
+
+	e := tConfOut{Hex: 255, Octal: 15, Binary: 5}
+
+	for _, d := range []string{
+		"{}\n",
+		"hex: \"0xFF\"\noctal: \"0o17\"\nbinary: \"0b101\"\n",
+		"hex: 0xFF\noctal: 0o17\nbinary: 0b101\n",
+		"hex: ENV:TEST_INT_HEX\noctal: \"0o1_7\"\nbinary: 0b1_01\n",
+	} {
+
+		var c tConfOut
+
+		os.Setenv("TEST_INT_HEX", "0xff")
+
+		if err := testLoadYAML(t, d, &c, Settings{}); err != nil {
+			t.Fatalf("Config load error for `%s`: %v", d, err)
+		}
+
+		os.Unsetenv("TEST_INT_HEX")
+
+		if c != e {
+			t.Fatalf("Incorrect loaded data for `%s`: %+v", d, c)
+		}
+	}
+
+	// Check JSON strings
+	var c tConfOut
+
+	if err := LoadBytes(&c, []byte(`{"hex": "0xFF", "octal": "0o17", "binary": "0b101"}`), Settings{ConfType: ConfigTypeJSON}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c != e {
+		t.Fatalf("Incorrect loaded data: %+v", c)
+	}
+}
+
+func TestWeaklyTypedFields(t *testing.T) {
+
+	type tConfBackend struct {
+		Port   int  `conf:"port"`
+		Weight *int `conf:"weight"`
+	}
+
+	type tConfOut struct {
+		Port     int            `conf:"port"`
+		Workers  int            `conf:"workers"`
+		Debug    bool           `conf:"debug"`
+		Backends []tConfBackend `conf:"backends"`
+	}
+
+	var c tConfOut
+
+	s := Settings{
+		WeaklyTypedFields: []string{"port", "debug", "backends.port", "backends.weight"},
+	}
+
+	// Strings are converted to other types anyway, so weak conversions are between other types
+	if err := testLoadYAML(t, "port: true\ndebug: 1\nworkers: 4\nbackends:\n  - port: true\n    weight: false\n", &c, s); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Port != 1 || c.Debug != true || c.Backends[0].Port != 1 || c.Backends[0].Weight == nil || *c.Backends[0].Weight != 0 {
+		t.Fatal("Incorrect loaded data:", c)
+	}
+
+	// Unlisted options are decoded without weak conversions
+	err := testLoadYAML(t, "port: true\nworkers: true\n", &c, s)
+	if err == nil || strings.Contains(err.Error(), "'workers'") == false {
+		t.Fatal("Expected decode error for unlisted option, got:", err)
+	}
+}
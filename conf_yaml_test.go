@@ -158,3 +158,84 @@ func testPrepareYAMLConfig(t *testing.T) {
 	// Set ENV variables
 	os.Setenv(testYAMLValStringEnvVar, testYAMLValString2)
 }
+
+func TestYAMLAnchors(t *testing.T) {
+
+	type tServer struct {
+		Host string `conf:"host" conf_extraopts:"required"`
+		Port int    `conf:"port" conf_extraopts:"default=8080"`
+	}
+
+	type tConfOut struct {
+		Servers     map[string]tServer  `conf:"servers"`
+		ServersPtrs map[string]*tServer `conf:"servers_ptrs"`
+	}
+
+	d := `
+base: &base
+  host: localhost
+servers:
+  one: *base
+  two:
+    <<: *base
+    port: 9090
+servers_ptrs:
+  one: *base
+  two: *base
+`
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, d, &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Servers["one"].Host != "localhost" || c.Servers["one"].Port != 8080 {
+		t.Fatal("Incorrect loaded data: Servers[one]")
+	}
+
+	if c.Servers["two"].Host != "localhost" || c.Servers["two"].Port != 9090 {
+		t.Fatal("Incorrect loaded data: Servers[two]")
+	}
+
+	if c.ServersPtrs["one"].Port != 8080 || c.ServersPtrs["two"].Port != 8080 {
+		t.Fatal("Incorrect loaded data: ServersPtrs")
+	}
+
+	// Check aliased elements do not share data (yaml decodes every alias into a separate value,
+	// so defaults are applied to each element independently)
+	if c.ServersPtrs["one"] == c.ServersPtrs["two"] {
+		t.Fatal("Incorrect loaded data: ServersPtrs elements are shared")
+	}
+}
+
+func TestYAMLDocumentIndex(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name"`
+	}
+
+	d := []byte("name: first\n---\nname: second\n")
+
+	var c tConfOut
+
+	if err := LoadBytes(&c, d, Settings{ConfType: ConfigTypeYAML}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "first" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	if err := LoadBytes(&c, d, Settings{ConfType: ConfigTypeYAML, DocumentIndex: 1}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "second" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	if err := LoadBytes(&c, d, Settings{ConfType: ConfigTypeYAML, DocumentIndex: 2}); err == nil {
+		t.Fatal("Expected error for missing document")
+	}
+}
@@ -0,0 +1,137 @@
+package conf
+
+import (
+	"reflect"
+)
+
+// FieldDoc contains description of config option
+type FieldDoc struct {
+
+	// Path is a dotted path to the option. Elements of slices and maps are denoted with `[]`
+	Path string
+
+	// Type is a Go type of the option
+	Type string
+
+	// Required is true if option is marked with `required` extra option
+	Required bool
+
+	// Default contains default value of the option if `HasDefault` is true
+	Default    string
+	HasDefault bool
+
+	// Desc is an option description specified with `desc` extra option
+	Desc string
+}
+
+// Describe returns descriptions of all options of config struct `conf` (e.g. to generate docs).
+// Options are listed in order of struct fields definition, parent options precede its sub-options
+func Describe(conf interface{}) []FieldDoc {
+
+	var (
+		s    Settings
+		docs []FieldDoc
+	)
+
+	s.describeType(reflect.TypeOf(conf), "", &docs, make(map[reflect.Type]bool))
+
+	return docs
+}
+
+// describeType appends descriptions of options of type `t` into `docs`
+func (s *Settings) describeType(t reflect.Type, parentName string, docs *[]FieldDoc, visited map[reflect.Type]bool) {
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+
+		// Options of types implementing `encoding.TextUnmarshaler` have no sub-options
+		if s.textUnmarshalerCheck(t) == true || visited[t] == true {
+			return
+		}
+
+		visited[t] = true
+		defer delete(visited, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			tf := t.Field(i)
+
+			// Remain fields collect options absent in struct, so they are not options themselves
+			if tf.PkgPath != "" || tf.Tag.Get(tagConfName) == "-" || s.fieldRemainCheck(tf) == true {
+				continue
+			}
+
+			elName := s.optNameJoin(parentName, tf)
+			tag := tf.Tag.Get(tagConfExtraOptsName)
+
+			// Squashed struct options are described as parent struct options
+			if s.fieldSquashCheck(tf) == true {
+				s.describeType(tf.Type, elName, docs, visited)
+				continue
+			}
+
+			d := FieldDoc{
+				Path:     elName,
+				Type:     tf.Type.String(),
+				Required: s.tagKeyCheck(tag, tagConfRequiredName),
+			}
+
+			d.Default, d.HasDefault = s.tagValGet(tag, tagConfDefaultName)
+			d.Desc, _ = s.tagValGet(tag, tagConfDescName)
+
+			*docs = append(*docs, d)
+
+			s.describeType(tf.Type, elName, docs, visited)
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		s.describeType(t.Elem(), parentName+"[]", docs, visited)
+	}
+}
+
+// Keys returns paths of all options of config struct `conf` (e.g. to generate docs or to cross-check config file keys).
+// Sub-options of nested structs are included, elements of slices and maps are not
+func Keys(conf interface{}) []string {
+
+	var (
+		s    Settings
+		keys []string
+	)
+
+	s.keysType(reflect.TypeOf(conf), "", &keys, make(map[reflect.Type]bool))
+
+	return keys
+}
+
+// keysType appends paths of options of type `t` into `keys`
+func (s *Settings) keysType(t reflect.Type, parentName string, keys *[]string, visited map[reflect.Type]bool) {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || s.textUnmarshalerCheck(t) == true || visited[t] == true {
+		return
+	}
+
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		tf := t.Field(i)
+
+		if tf.PkgPath != "" || tf.Tag.Get(tagConfName) == "-" || s.fieldRemainCheck(tf) == true {
+			continue
+		}
+
+		elName := s.optNameJoin(parentName, tf)
+
+		if s.fieldSquashCheck(tf) == false {
+			*keys = append(*keys, elName)
+		}
+
+		s.keysType(tf.Type, elName, keys, visited)
+	}
+}
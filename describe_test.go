@@ -0,0 +1,69 @@
+package conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+
+	type tConfServer struct {
+		Host string `conf:"host" conf_extraopts:"required,desc=Server host"`
+		Port int    `conf:"port" conf_extraopts:"default=8080,desc=The listen port"`
+	}
+
+	type tConfOut struct {
+		Server  tConfServer   `conf:"server"`
+		Backups []tConfServer `conf:"backups" conf_extraopts:"desc=Backup servers\\, if any"`
+		Source  string        `conf:"-" conf_extraopts:"source_path"`
+	}
+
+	docs := Describe(&tConfOut{})
+
+	exp := []FieldDoc{
+		{Path: "server", Type: "conf.tConfServer"},
+		{Path: "server.host", Type: "string", Required: true, Desc: "Server host"},
+		{Path: "server.port", Type: "int", Default: "8080", HasDefault: true, Desc: "The listen port"},
+		{Path: "backups", Type: "[]conf.tConfServer", Desc: "Backup servers, if any"},
+		{Path: "backups[].host", Type: "string", Required: true, Desc: "Server host"},
+		{Path: "backups[].port", Type: "int", Default: "8080", HasDefault: true, Desc: "The listen port"},
+	}
+
+	if reflect.DeepEqual(docs, exp) == false {
+		t.Fatalf("Incorrect description: %+v", docs)
+	}
+}
+
+func TestKeys(t *testing.T) {
+
+	type tConfOut struct {
+		StringTest string `conf:"string_test"`
+		StructTest struct {
+			StringTest string `conf:"string_test"`
+			IntTest    int    `conf:"int_test"`
+		} `conf:"struct_test"`
+		PtrTest *struct {
+			BoolTest bool `conf:"bool_test"`
+		} `conf:"ptr_test"`
+		SliceTest []struct {
+			StringTest string `conf:"string_test"`
+		} `conf:"slice_test"`
+		MapTest map[string]string `conf:"map_test"`
+		Skipped string            `conf:"-"`
+	}
+
+	exp := []string{
+		"string_test",
+		"struct_test",
+		"struct_test.string_test",
+		"struct_test.int_test",
+		"ptr_test",
+		"ptr_test.bool_test",
+		"slice_test",
+		"map_test",
+	}
+
+	if k := Keys(&tConfOut{}); reflect.DeepEqual(k, exp) == false {
+		t.Fatal("Incorrect keys:", k)
+	}
+}
@@ -0,0 +1,64 @@
+package conf
+
+import (
+	"reflect"
+)
+
+// DiffFromDefaults returns options of populated config struct `conf` with values differ from its default values
+// (e.g. to log effective overrides) as paths to options with its current values. Options without default values
+// are compared with zero values. Default values that can't be applied (e.g. referencing unset ENV variables) are treated as zero values
+func DiffFromDefaults(conf interface{}) map[string]interface{} {
+
+	var s Settings
+
+	s.sources = make(map[string]string)
+
+	t := reflect.TypeOf(conf)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	d := reflect.New(t)
+
+	// Config with default values only is the same as the config loaded from an empty file
+	s.setDefaults(d, "", defaultValue{"", false, ""}, 1)
+	s.setKeyDefaults(d)
+
+	r := make(map[string]interface{})
+
+	// Config may be passed either by value or by pointer
+	s.diffValue(reflect.Indirect(reflect.ValueOf(conf)), d.Elem(), "", r)
+
+	return r
+}
+
+// diffValue puts value `cur` with path `name` (or its sub-options) into `r` if it differs from default value `def`
+func (s *Settings) diffValue(cur, def reflect.Value, name string, r map[string]interface{}) {
+
+	for cur.Kind() == reflect.Ptr && def.Kind() == reflect.Ptr {
+		if cur.IsNil() == true || def.IsNil() == true {
+			if cur.IsNil() != def.IsNil() {
+				r[name] = cur.Interface()
+			}
+			return
+		}
+		cur, def = cur.Elem(), def.Elem()
+	}
+
+	if cur.Kind() != reflect.Struct || s.textUnmarshalerCheck(cur.Type()) == true {
+		if reflect.DeepEqual(cur.Interface(), def.Interface()) == false {
+			r[name] = cur.Interface()
+		}
+		return
+	}
+
+	for i := 0; i < cur.NumField(); i++ {
+		tf := cur.Type().Field(i)
+
+		if tf.PkgPath != "" || tf.Tag.Get(tagConfName) == "-" {
+			continue
+		}
+
+		s.diffValue(cur.Field(i), def.Field(i), s.optNameJoin(name, tf), r)
+	}
+}
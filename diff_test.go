@@ -0,0 +1,45 @@
+package conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffFromDefaults(t *testing.T) {
+
+	type tConfDB struct {
+		Host string `conf:"host" conf_extraopts:"default=localhost"`
+		Port int    `conf:"port" conf_extraopts:"default=5432"`
+	}
+
+	type tConfOut struct {
+		Name     string            `conf:"name"`
+		Debug    bool              `conf:"debug"`
+		Workers  int               `conf:"workers" conf_extraopts:"default=4"`
+		Labels   map[string]string `conf:"labels" conf_extraopts:"default=env=prod"`
+		Database tConfDB           `conf:"database"`
+		Cache    *tConfDB          `conf:"cache"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "name: test\nworkers: 4\ndatabase:\n  port: 6432\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	d := DiffFromDefaults(&c)
+
+	e := map[string]interface{}{
+		"name":          "test",
+		"database.port": 6432,
+	}
+
+	if reflect.DeepEqual(d, e) == false {
+		t.Fatal("Incorrect diff from defaults:", d)
+	}
+
+	// Config may be passed by value
+	if d := DiffFromDefaults(c); reflect.DeepEqual(d, e) == false {
+		t.Fatal("Incorrect diff from defaults:", d)
+	}
+}
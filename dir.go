@@ -0,0 +1,110 @@
+package conf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+// LoadDir reads all config files within directory `dir` matching `DirPattern` in order of its names
+// and merges them, so options of later files override options of earlier ones. Settings field `ConfPath` is ignored.
+// With `ConfigTypeAuto` the format is detected by every file extension and files of unknown types are skipped
+func LoadDir(conf interface{}, dir string, s Settings) error {
+
+	// Check `conf` is a pointer
+	if reflect.TypeOf(conf).Kind() != reflect.Ptr {
+		return fmt.Errorf("config load internal error: `conf` must be a pointer")
+	}
+
+	files, err := s.dirFiles(dir)
+	if err != nil {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	if len(files) == 0 && s.DirEmptyAllow == false {
+		return fmt.Errorf("config error: no config files found in directory '%s'", dir)
+	}
+
+	s.includes = s.includesCheck(conf)
+
+	var rawConf interface{} = make(map[string]interface{})
+
+	for _, f := range files {
+
+		cfgFile, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			return fmt.Errorf("config error: %v", err)
+		}
+
+		r, err := s.rawUnmarshal(cfgFile, f.t)
+		if err != nil {
+			return fmt.Errorf("config error: file '%s': %w", f.path, err)
+		}
+
+		if s.includes == true {
+			if r, err = s.rawIncludesResolve(r, f.path, []string{s.includePath(f.path, "")}); err != nil {
+				return fmt.Errorf("config error: %v", err)
+			}
+		}
+
+		rawConf = s.rawMerge(rawConf, r)
+	}
+
+	s.sourcePath = dir
+
+	return confRawRead(conf, rawConf.(map[string]interface{}), &s)
+}
+
+type dirFile struct {
+	path string
+	t    ConfigType
+}
+
+// dirFiles returns config files within directory `dir` matching `DirPattern` sorted by names
+func (s *Settings) dirFiles(dir string) ([]dirFile, error) {
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	var files []dirFile
+
+	for _, e := range entries {
+
+		if e.IsDir() == true {
+			continue
+		}
+
+		if s.DirPattern != "" {
+			ok, err := filepath.Match(s.DirPattern, e.Name())
+			if err != nil {
+				return nil, err
+			}
+			if ok == false {
+				continue
+			}
+		}
+
+		f := dirFile{
+			path: filepath.Join(dir, e.Name()),
+			t:    s.ConfType,
+		}
+
+		if f.t == ConfigTypeAuto {
+			if f.t, err = confTypeDetect(f.path); err != nil {
+				continue
+			}
+		}
+
+		files = append(files, f)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,79 @@
+package conf
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDir(t *testing.T) {
+
+	type tConfOut struct {
+		Name     string `conf:"name" conf_extraopts:"required"`
+		Debug    bool   `conf:"debug"`
+		Database struct {
+			Host string `conf:"host" conf_extraopts:"required"`
+			Port int    `conf:"port" conf_extraopts:"default=5432"`
+			User string `conf:"user"`
+		} `conf:"database" conf_extraopts:"required"`
+	}
+
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"10-base.yml":     "name: test\ndatabase:\n  host: localhost\n  user: admin\n",
+		"20-override.yml": "database:\n  host: db.local\n",
+		"30-debug.json":   "{\"debug\": true}",
+		"README.txt":      "not a config",
+	}
+
+	for n, d := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, n), []byte(d), 0644); err != nil {
+			t.Fatal("Config file prepare error:", err)
+		}
+	}
+
+	var c tConfOut
+
+	if err := LoadDir(&c, dir, Settings{ConfType: ConfigTypeAuto, UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "test" || c.Debug != true {
+		t.Fatal("Incorrect loaded data: Name, Debug")
+	}
+
+	if c.Database.Host != "db.local" || c.Database.Port != 5432 || c.Database.User != "admin" {
+		t.Fatal("Incorrect loaded data: Database")
+	}
+
+	// Check pattern
+	var p tConfOut
+
+	if err := LoadDir(&p, dir, Settings{ConfType: ConfigTypeYAML, DirPattern: "*.yml"}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if p.Debug != false || p.Database.Host != "db.local" {
+		t.Fatal("Incorrect loaded data: pattern")
+	}
+
+	// Check empty directory
+	type tConfEmpty struct {
+		Port int `conf:"port" conf_extraopts:"default=80"`
+	}
+
+	var e tConfEmpty
+
+	if err := LoadDir(&e, t.TempDir(), Settings{}); err == nil {
+		t.Fatal("Expected empty directory error")
+	}
+
+	if err := LoadDir(&e, t.TempDir(), Settings{DirEmptyAllow: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if e.Port != 80 {
+		t.Fatal("Incorrect loaded data: empty directory")
+	}
+}
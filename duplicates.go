@@ -0,0 +1,143 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// keysDuplicatesCheck checks config data `cfgFile` of type `t` has no keys specified more than once
+// at the same level. Only YAML and JSON (including JSON5) data is checked
+func (s *Settings) keysDuplicatesCheck(cfgFile []byte, t ConfigType) error {
+
+	switch t {
+	case ConfigTypeYAML:
+		return s.yamlDuplicatesCheck(cfgFile, s.DocumentIndex)
+	case ConfigTypeJSON:
+		return s.jsonDuplicatesCheck(cfgFile)
+	case ConfigTypeJSON5:
+		d, err := jsonCommentsStrip(cfgFile)
+		if err != nil {
+			return err
+		}
+		return s.jsonDuplicatesCheck(d)
+	case ConfigTypeAuto:
+		if json.Valid(cfgFile) == true {
+			return s.jsonDuplicatesCheck(cfgFile)
+		}
+		return s.yamlDuplicatesCheck(cfgFile, 0)
+	}
+
+	return nil
+}
+
+// yamlDuplicatesCheck checks document `idx` of YAML stream `data` has no duplicated keys
+func (s *Settings) yamlDuplicatesCheck(data []byte, idx int) error {
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	for i := 0; i <= idx; i++ {
+
+		var m yaml.MapSlice
+
+		if err := decoder.Decode(&m); err != nil {
+			// Parse errors are reported on unmarshal
+			return nil
+		}
+
+		if i == idx {
+			return s.yamlDuplicatesWalk(m, "")
+		}
+	}
+
+	return nil
+}
+
+func (s *Settings) yamlDuplicatesWalk(v interface{}, parentName string) error {
+
+	switch e := v.(type) {
+	case yaml.MapSlice:
+		keys := make(map[string]bool)
+		for _, i := range e {
+			name := s.optPathJoin(parentName, fmt.Sprintf("%v", i.Key))
+			if keys[name] == true {
+				return fmt.Errorf("key '%s' is specified more than once", name)
+			}
+			keys[name] = true
+			if err := s.yamlDuplicatesWalk(i.Value, name); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, el := range e {
+			if err := s.yamlDuplicatesWalk(el, fmt.Sprintf("%s[%d]", parentName, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// jsonDuplicatesCheck checks JSON `data` has no duplicated keys
+func (s *Settings) jsonDuplicatesCheck(data []byte) error {
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	err := s.jsonDuplicatesWalk(decoder, "")
+	if err == io.EOF {
+		// Parse errors are reported on unmarshal
+		return nil
+	}
+	if _, ok := err.(*json.SyntaxError); ok == true {
+		return nil
+	}
+
+	return err
+}
+
+func (s *Settings) jsonDuplicatesWalk(decoder *json.Decoder, parentName string) error {
+
+	t, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t {
+	case json.Delim('{'):
+		keys := make(map[string]bool)
+		for decoder.More() == true {
+			k, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			name := s.optPathJoin(parentName, fmt.Sprintf("%v", k))
+			if keys[name] == true {
+				return fmt.Errorf("key '%s' is specified more than once", name)
+			}
+			keys[name] = true
+			if err := s.jsonDuplicatesWalk(decoder, name); err != nil {
+				return err
+			}
+		}
+		// Closing brace
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	case json.Delim('['):
+		for i := 0; decoder.More() == true; i++ {
+			if err := s.jsonDuplicatesWalk(decoder, fmt.Sprintf("%s[%d]", parentName, i)); err != nil {
+				return err
+			}
+		}
+		// Closing bracket
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
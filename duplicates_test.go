@@ -0,0 +1,65 @@
+package conf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDuplicateKeyDeny(t *testing.T) {
+
+	type tConfOut struct {
+		Name     string `conf:"name"`
+		Database struct {
+			Host string `conf:"host"`
+			Port int    `conf:"port"`
+		} `conf:"database"`
+		Backups []struct {
+			Host string `conf:"host"`
+		} `conf:"backups"`
+	}
+
+	tests := []struct {
+		t    ConfigType
+		data string
+		path string
+	}{
+		{ConfigTypeYAML, "name: test\ndatabase:\n  host: a\n  port: 1\n  host: b\n", "database.host"},
+		{ConfigTypeYAML, "backups:\n  - host: a\n    host: b\n", "backups[0].host"},
+		{ConfigTypeJSON, "{\"name\": \"test\", \"database\": {\"host\": \"a\"}, \"name\": \"test2\"}", "name"},
+		{ConfigTypeJSON, "{\"backups\": [{\"host\": \"a\"}, {\"host\": \"b\", \"host\": \"c\"}]}", "backups[1].host"},
+	}
+
+	for _, e := range tests {
+
+		var c tConfOut
+
+		// Last value is used by default
+		if err := LoadBytes(&c, []byte(e.data), Settings{
+			ConfType: e.t,
+		}); err != nil {
+			t.Fatal("Config load error:", err)
+		}
+
+		err := LoadBytes(&c, []byte(e.data), Settings{
+			ConfType:         e.t,
+			DuplicateKeyDeny: true,
+		})
+		if err == nil {
+			t.Fatal("Expected duplicated key error for:", e.data)
+		}
+
+		if strings.Contains(err.Error(), "'"+e.path+"'") == false {
+			t.Fatal("Incorrect error:", err)
+		}
+	}
+
+	// Check valid config
+	var c tConfOut
+
+	if err := LoadBytes(&c, []byte("name: test\ndatabase:\n  host: a\nbackups:\n  - host: a\n  - host: b\n"), Settings{
+		ConfType:         ConfigTypeYAML,
+		DuplicateKeyDeny: true,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+}
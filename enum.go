@@ -0,0 +1,46 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// enums contains registered names of enum types values
+var enums = struct {
+	sync.RWMutex
+	m map[reflect.Type]map[string]int64
+}{
+	m: make(map[reflect.Type]map[string]int64),
+}
+
+// RegisterEnum registers value names for custom integer type of `proto`.
+// Options of this type may be specified in config either by name or by numeric value,
+// names are matched case-insensitively
+func RegisterEnum(proto interface{}, names map[string]int64) {
+
+	n := make(map[string]int64)
+	for k, v := range names {
+		n[strings.ToLower(k)] = v
+	}
+
+	enums.Lock()
+	defer enums.Unlock()
+
+	enums.m[reflect.TypeOf(proto)] = n
+}
+
+// enumValueGet gets value of registered enum type `t` by `name`
+func enumValueGet(t reflect.Type, name string) (int64, bool) {
+
+	enums.RLock()
+	defer enums.RUnlock()
+
+	n, ok := enums.m[t]
+	if ok == false {
+		return 0, false
+	}
+
+	v, ok := n[strings.ToLower(name)]
+	return v, ok
+}
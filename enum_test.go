@@ -0,0 +1,49 @@
+package conf
+
+import (
+	"testing"
+)
+
+type tEnumState int
+
+const (
+	tEnumStateInactive tEnumState = 0
+	tEnumStateActive   tEnumState = 1
+)
+
+func TestEnum(t *testing.T) {
+
+	RegisterEnum(tEnumState(0), map[string]int64{
+		"inactive": int64(tEnumStateInactive),
+		"active":   int64(tEnumStateActive),
+	})
+
+	type tConfOut struct {
+		ByName   tEnumState `conf:"by_name"`
+		ByNumber tEnumState `conf:"by_number"`
+		Default  tEnumState `conf:"default" conf_extraopts:"default=Active"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "by_name: active\nby_number: 1\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.ByName != tEnumStateActive {
+		t.Fatal("Incorrect loaded data: ByName")
+	}
+
+	if c.ByNumber != tEnumStateActive {
+		t.Fatal("Incorrect loaded data: ByNumber")
+	}
+
+	if c.Default != tEnumStateActive {
+		t.Fatal("Incorrect loaded data: Default")
+	}
+
+	// Check unknown name
+	if err := testLoadYAML(t, "by_name: unknown\n", &c, Settings{}); err == nil {
+		t.Fatal("Expected error for unknown enum name")
+	}
+}
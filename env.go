@@ -0,0 +1,94 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// LoadEnv reads config from ENV variables only. Variable name of every option is `prefix` followed by
+// uppercased option path with underscores instead of dots (e.g. option `db.host` with `APP_` prefix is read
+// from `APP_DB_HOST`). Default values and extra options checks are applied the same way as for `Load`.
+// Settings field `ConfPath` is ignored
+func LoadEnv(conf interface{}, prefix string, s Settings) error {
+
+	// Check `conf` is a pointer
+	if reflect.TypeOf(conf).Kind() != reflect.Ptr {
+		return fmt.Errorf("config load internal error: `conf` must be a pointer")
+	}
+
+	rawConf := s.envRawGet(reflect.TypeOf(conf), prefix, make(map[reflect.Type]bool))
+	if rawConf == nil {
+		rawConf = make(map[string]interface{})
+	}
+
+	return confRawRead(conf, rawConf, &s)
+}
+
+// envRawGet returns raw map with values of set ENV variables for options of struct type `t` with variables names
+// started with `prefix`, or nil if no variables are set. `visited` contains struct types being processed to stop on recursive types
+func (s *Settings) envRawGet(t reflect.Type, prefix string, visited map[reflect.Type]bool) map[string]interface{} {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || s.textUnmarshalerCheck(t) == true || visited[t] == true {
+		return nil
+	}
+
+	visited[t] = true
+	defer delete(visited, t)
+
+	var r map[string]interface{}
+
+	set := func(k string, v interface{}) {
+		if r == nil {
+			r = make(map[string]interface{})
+		}
+		r[k] = v
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		tf := t.Field(i)
+
+		if tf.PkgPath != "" || s.fieldRemainCheck(tf) == true {
+			continue
+		}
+
+		// Squashed struct options are contained in the same raw map
+		if s.fieldSquashCheck(tf) == true {
+			for k, v := range s.envRawGet(tf.Type, prefix, visited) {
+				set(k, v)
+			}
+			continue
+		}
+
+		name := s.fieldNameNormalize(tf)
+		if name == "-" {
+			continue
+		}
+
+		env := prefix + strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+
+		ft := tf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		// Nested struct options are set only if any of its variables is set, so pointers are kept nil otherwise
+		if ft.Kind() == reflect.Struct && s.textUnmarshalerCheck(ft) == false {
+			if sub := s.envRawGet(ft, env+"_", visited); sub != nil {
+				set(name, sub)
+			}
+			continue
+		}
+
+		if v, ok := os.LookupEnv(env); ok == true {
+			set(name, v)
+		}
+	}
+
+	return r
+}
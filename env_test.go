@@ -0,0 +1,59 @@
+package conf
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadEnv(t *testing.T) {
+
+	type tConfDB struct {
+		Host string `conf:"host" conf_extraopts:"required"`
+		Port int    `conf:"port" conf_extraopts:"default=5432"`
+	}
+
+	type tConfOut struct {
+		Name     string   `conf:"name" conf_extraopts:"required"`
+		Debug    bool     `conf:"debug"`
+		Database tConfDB  `conf:"database"`
+		Cache    *tConfDB `conf:"cache"`
+	}
+
+	env := map[string]string{
+		"APP_NAME":          "test",
+		"APP_DEBUG":         "true",
+		"APP_DATABASE_HOST": "db.local",
+	}
+
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	var c tConfOut
+
+	if err := LoadEnv(&c, "APP_", Settings{UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "test" || c.Debug != true {
+		t.Fatal("Incorrect loaded data: top-level options")
+	}
+
+	if c.Database.Host != "db.local" || c.Database.Port != 5432 {
+		t.Fatal("Incorrect loaded data: nested options")
+	}
+
+	if c.Cache != nil {
+		t.Fatal("Incorrect loaded data: absent nested options")
+	}
+
+	// Check missing required variable
+	os.Unsetenv("APP_DATABASE_HOST")
+
+	err := LoadEnv(&tConfOut{}, "APP_", Settings{})
+	if err == nil || strings.Contains(err.Error(), "required option 'database.host' is not specified") == false {
+		t.Fatal("Incorrect required option error:", err)
+	}
+}
@@ -0,0 +1,123 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Available categories of config load errors
+const (
+	// ErrorCategoryParse is an error of config file reading, parsing or options values decoding
+	ErrorCategoryParse = 0
+
+	// ErrorCategoryRequired is an error of required options absence
+	ErrorCategoryRequired = 1
+
+	// ErrorCategoryValidation is an error of options values validation (including `Validator` errors)
+	ErrorCategoryValidation = 2
+
+	// ErrorCategoryUnknown is an error of unknown options presence
+	ErrorCategoryUnknown = 3
+)
+
+// ErrorCategory is a category of config load error
+type ErrorCategory int
+
+// String returns name of the error category
+func (c ErrorCategory) String() string {
+
+	switch c {
+	case ErrorCategoryParse:
+		return "parse"
+	case ErrorCategoryRequired:
+		return "required"
+	case ErrorCategoryValidation:
+		return "validation"
+	case ErrorCategoryUnknown:
+		return "unknown"
+	}
+
+	return fmt.Sprintf("category %d", int(c))
+}
+
+// LoadError is a config load error of specific category
+type LoadError struct {
+	Category ErrorCategory
+	Err      error
+}
+
+func (e *LoadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// LoadResult contains result of `LoadDetailed`
+type LoadResult struct {
+
+	// Errors contains all errors found while loading
+	Errors []*LoadError
+
+	// Warnings contains warnings found while loading (see `LoadWithWarnings`)
+	Warnings []string
+}
+
+// Err returns an error containing all load errors, or nil if config is loaded successfully
+func (r LoadResult) Err() error {
+
+	if len(r.Errors) == 0 {
+		return nil
+	}
+
+	var e []string
+	for _, l := range r.Errors {
+		e = append(e, fmt.Sprintf("%s: %v", l.Category, l.Err))
+	}
+
+	return fmt.Errorf("config error: %s", strings.Join(e, "; "))
+}
+
+// HasCategory checks result contains errors of category `c`
+func (r LoadResult) HasCategory(c ErrorCategory) bool {
+
+	for _, l := range r.Errors {
+		if l.Category == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadDetailed reads config the same way as `Load`, but doesn't stop at first decoding or validation error.
+// All errors are collected into result with its categories (e.g. to map categories to CLI exit codes).
+// Errors of config file reading and parsing still stop loading. `RequiredCheckFirst` settings field is ignored
+func LoadDetailed(conf interface{}, s Settings) LoadResult {
+
+	s.errsCollect = true
+
+	if err := load(conf, &s); err != nil {
+		s.errs = append(s.errs, &LoadError{Category: ErrorCategoryParse, Err: errors.New(strings.TrimPrefix(err.Error(), "config error: "))})
+	}
+
+	return LoadResult{
+		Errors:   s.errs,
+		Warnings: s.warnings,
+	}
+}
+
+// errorCollect collects error `err` of category `c` if errors collecting is enabled.
+// Returns false if errors collecting is disabled, so loading must be stopped
+func (s *Settings) errorCollect(c ErrorCategory, err error) bool {
+
+	if s.errsCollect == false {
+		return false
+	}
+
+	s.errs = append(s.errs, &LoadError{Category: c, Err: err})
+
+	return true
+}
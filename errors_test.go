@@ -0,0 +1,55 @@
+package conf
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDetailed(t *testing.T) {
+
+	type tConfOut struct {
+		Port int    `conf:"port"`
+		Host string `conf:"host" conf_extraopts:"required"`
+	}
+
+	p := filepath.Join(t.TempDir(), "conf.yml")
+
+	if err := ioutil.WriteFile(p, []byte("port: abc\nextra: 1\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	var c tConfOut
+
+	r := LoadDetailed(&c, Settings{ConfPath: p, ConfType: ConfigTypeYAML, UnknownDeny: true})
+
+	if r.Err() == nil {
+		t.Fatal("Expected load error")
+	}
+
+	if r.HasCategory(ErrorCategoryParse) == false {
+		t.Fatal("Expected parse error:", r.Err())
+	}
+
+	if r.HasCategory(ErrorCategoryRequired) == false {
+		t.Fatal("Expected required error:", r.Err())
+	}
+
+	if r.HasCategory(ErrorCategoryValidation) == true {
+		t.Fatal("Unexpected validation error:", r.Err())
+	}
+
+	r = LoadDetailed(&c, Settings{ConfPath: filepath.Join(t.TempDir(), "missing.yml"), ConfType: ConfigTypeYAML})
+
+	if len(r.Errors) != 1 || r.Errors[0].Category != ErrorCategoryParse {
+		t.Fatal("Expected single parse error:", r.Err())
+	}
+
+	if err := ioutil.WriteFile(p, []byte("port: 80\nhost: localhost\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	if r = LoadDetailed(&c, Settings{ConfPath: p, ConfType: ConfigTypeYAML}); r.Err() != nil {
+		t.Fatal("Config load error:", r.Err())
+	}
+}
@@ -0,0 +1,125 @@
+package conf
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+)
+
+// RegisterFlags registers string flags in `fs` for options of config struct `conf` (except nested structs itself).
+// Flags names are options paths (e.g. `-db.host`), usages are options descriptions (see `desc` extra option).
+// Pass `fs` in `Flags` settings field to override options values with flags set in command line
+func RegisterFlags(fs *flag.FlagSet, conf interface{}) {
+
+	var s Settings
+
+	s.flagsRegister(fs, reflect.TypeOf(conf), "", make(map[reflect.Type]bool))
+}
+
+// flagsRegister registers flags in `fs` for options of type `t`
+func (s *Settings) flagsRegister(fs *flag.FlagSet, t reflect.Type, parentName string, visited map[reflect.Type]bool) {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || s.textUnmarshalerCheck(t) == true || visited[t] == true {
+		return
+	}
+
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		tf := t.Field(i)
+
+		if tf.PkgPath != "" || tf.Tag.Get(tagConfName) == "-" || s.fieldRemainCheck(tf) == true {
+			continue
+		}
+
+		elName := s.optNameJoin(parentName, tf)
+
+		ft := tf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct && s.textUnmarshalerCheck(ft) == false {
+			s.flagsRegister(fs, ft, elName, visited)
+			continue
+		}
+
+		tag := tf.Tag.Get(tagConfExtraOptsName)
+
+		d, _ := s.tagValGet(tag, tagConfDefaultName)
+		desc, _ := s.tagValGet(tag, tagConfDescName)
+
+		fs.String(elName, d, desc)
+	}
+}
+
+// flagsOverlay sets options within raw config data `rawConf` of struct type `t` to values of flags
+// set in command line with names equal to options paths
+func (s *Settings) flagsOverlay(rawConf map[string]interface{}, t reflect.Type) error {
+
+	var keys []string
+
+	s.keysType(t, "", &keys, make(map[reflect.Type]bool))
+
+	opts := make(map[string]bool)
+	for _, k := range keys {
+		opts[k] = true
+	}
+
+	var err error
+
+	s.Flags.Visit(func(f *flag.Flag) {
+		if err != nil || opts[f.Name] == false {
+			return
+		}
+		if err = s.rawValueSet(rawConf, s.optPathSplit(f.Name), f.Value.String()); err != nil {
+			err = fmt.Errorf("flag '%s': %v", f.Name, err)
+			return
+		}
+		s.sources[f.Name] = SourceFlag
+	})
+
+	return err
+}
+
+// rawValueSet sets value with path `path` within raw map `m` to `v`. Absent nested maps are created
+func (s *Settings) rawValueSet(m map[string]interface{}, path []string, v interface{}) error {
+
+	rv := reflect.ValueOf(m)
+
+	for i, e := range path {
+
+		k, ok := s.rawMapKey(rv, e)
+		if ok == false {
+			k = reflect.ValueOf(e)
+		}
+
+		if i == len(path)-1 {
+			rv.SetMapIndex(k, reflect.ValueOf(v))
+			break
+		}
+
+		n := rv.MapIndex(k)
+		if n.IsValid() == false || n.IsNil() == true {
+			n = reflect.ValueOf(make(map[string]interface{}))
+			rv.SetMapIndex(k, n)
+		}
+
+		if n.Kind() == reflect.Interface {
+			n = n.Elem()
+		}
+
+		if n.Kind() != reflect.Map {
+			return fmt.Errorf("option '%s' is not a map", e)
+		}
+
+		rv = n
+	}
+
+	return nil
+}
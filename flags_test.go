@@ -0,0 +1,66 @@
+package conf
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+
+	type tConfOut struct {
+		Name     string `conf:"name" conf_extraopts:"env=TEST_FLAGS_NAME"`
+		Debug    bool   `conf:"debug"`
+		Database struct {
+			Host string `conf:"host" conf_extraopts:"required,desc=Database host"`
+			Port int    `conf:"port" conf_extraopts:"default=5432"`
+		} `conf:"database"`
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	config := fs.String("config", "", "Config file path")
+
+	RegisterFlags(fs, &tConfOut{})
+
+	if f := fs.Lookup("database.host"); f == nil || f.Usage != "Database host" {
+		t.Fatal("Incorrect registered flag: database.host")
+	}
+
+	if f := fs.Lookup("database.port"); f == nil || f.DefValue != "5432" {
+		t.Fatal("Incorrect registered flag: database.port")
+	}
+
+	if fs.Lookup("database") != nil {
+		t.Fatal("Unexpected flag for nested struct")
+	}
+
+	p := filepath.Join(t.TempDir(), "conf.yml")
+	if err := ioutil.WriteFile(p, []byte("name: file\ndatabase:\n  host: file.local\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	os.Setenv("TEST_FLAGS_NAME", "env")
+	defer os.Unsetenv("TEST_FLAGS_NAME")
+
+	if err := fs.Parse([]string{"-config", p, "-database.host", "flag.local", "-name", "flag"}); err != nil {
+		t.Fatal("Flags parse error:", err)
+	}
+
+	var c tConfOut
+
+	m, err := LoadWithMeta(&c, Settings{ConfPath: *config, ConfType: ConfigTypeYAML, Flags: fs, UnknownDeny: true})
+	if err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	// Flags take precedence over config file and ENV variables, options without flags set are kept
+	if c.Name != "flag" || c.Database.Host != "flag.local" || c.Database.Port != 5432 || c.Debug != false {
+		t.Fatal("Incorrect loaded data:", c)
+	}
+
+	if m.Sources["database.host"] != SourceFlag || m.Sources["database.port"] != SourceDefault {
+		t.Fatal("Incorrect options sources:", m.Sources)
+	}
+}
@@ -0,0 +1,152 @@
+package conf
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// redactedValue replaces values of secret options in `Redacted` result
+const redactedValue = "****"
+
+// Flatten returns options of populated config struct `conf` as dotted paths to options with its stringified values
+// (e.g. for flat config stores). Elements of slices and maps are denoted as `name[index]` and `name[key]`.
+// Options with nil values are omitted
+func Flatten(conf interface{}) map[string]string {
+
+	var s Settings
+
+	r := make(map[string]string)
+
+	s.flattenValue(reflect.ValueOf(conf), "", r, false)
+
+	return r
+}
+
+// Redacted returns options of populated config struct `conf` in format `path=value`, one option per line
+// sorted by paths (e.g. for logging). Values of options marked with `secret` extra option
+// (including its sub-options) are replaced with `****`
+func Redacted(conf interface{}) string {
+
+	var s Settings
+
+	r := make(map[string]string)
+
+	s.flattenValue(reflect.ValueOf(conf), "", r, true)
+
+	keys := make([]string, 0, len(r))
+	for k := range r {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, r[k])
+	}
+
+	return b.String()
+}
+
+// flattenValue puts stringified value `val` with path `name` (or its sub-options) into `r`.
+// If `redact` is true values of secret options are masked
+func (s *Settings) flattenValue(val reflect.Value, name string, r map[string]string, redact bool) {
+
+	if val.IsValid() == false {
+		return
+	}
+
+	if (val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface) && val.IsNil() == true {
+		return
+	}
+
+	if m, ok := val.Interface().(encoding.TextMarshaler); ok == true {
+		if b, err := m.MarshalText(); err == nil {
+			r[name] = string(b)
+			return
+		}
+	}
+
+	// Values of types like `sql.NullString` are stringified by its driver values, invalid ones are omitted
+	if v, ok := val.Interface().(driver.Valuer); ok == true {
+		if d, err := v.Value(); err == nil {
+			if d != nil {
+				r[name] = fmt.Sprint(d)
+			}
+			return
+		}
+	}
+
+	// Interfaces may contain pointers (e.g. polymorphic options), so values are unwrapped recursively
+	if val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		s.flattenValue(val.Elem(), name, r, redact)
+		return
+	}
+
+	// URLs are stringified as a whole
+	if val.Type() == urlType {
+		u := val.Interface().(url.URL)
+		r[name] = u.String()
+		return
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			tf := val.Type().Field(i)
+
+			if tf.PkgPath != "" || tf.Tag.Get(tagConfName) == "-" {
+				continue
+			}
+
+			// Options collected into remain field are flattened as options of the struct
+			if s.fieldRemainCheck(tf) == true {
+				s.flattenRemain(val.Field(i), name, r, redact)
+				continue
+			}
+
+			elName := s.optNameJoin(name, tf)
+
+			if redact == true && s.tagKeyCheck(tf.Tag.Get(tagConfExtraOptsName), tagConfSecretName) == true {
+				r[elName] = redactedValue
+				continue
+			}
+
+			s.flattenValue(val.Field(i), elName, r, redact)
+		}
+	case reflect.Slice, reflect.Array:
+
+		// Byte slices are encoded the same way they are decoded
+		if s.bytesCheck(val.Type()) == true {
+			r[name] = base64.StdEncoding.EncodeToString(val.Bytes())
+			return
+		}
+
+		for i := 0; i < val.Len(); i++ {
+			s.flattenValue(val.Index(i), fmt.Sprintf("%s[%d]", name, i), r, redact)
+		}
+	case reflect.Map:
+		for _, k := range val.MapKeys() {
+			s.flattenValue(val.MapIndex(k), fmt.Sprintf("%s[%v]", name, k), r, redact)
+		}
+	default:
+		r[name] = fmt.Sprint(val.Interface())
+	}
+}
+
+// flattenRemain puts options of remain field map `val` of struct with path `name` into `r`
+func (s *Settings) flattenRemain(val reflect.Value, name string, r map[string]string, redact bool) {
+
+	if val.Kind() != reflect.Map {
+		return
+	}
+
+	for _, k := range val.MapKeys() {
+		s.flattenValue(val.MapIndex(k), s.optPathJoin(name, fmt.Sprint(k.Interface())), r, redact)
+	}
+}
@@ -0,0 +1,114 @@
+package conf
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFlatten(t *testing.T) {
+
+	type tConfServer struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port"`
+	}
+
+	type tConfOut struct {
+		Server  tConfServer            `conf:"server"`
+		Backups []tConfServer          `conf:"backups"`
+		Labels  map[string]string      `conf:"labels"`
+		Limits  map[string]tConfServer `conf:"limits"`
+		Timeout time.Duration          `conf:"timeout"`
+		IP      net.IP                 `conf:"ip"`
+		Key     []byte                 `conf:"key"`
+		Sub     *tConfServer           `conf:"sub"`
+	}
+
+	c := tConfOut{
+		Server:  tConfServer{Host: "localhost", Port: 80},
+		Backups: []tConfServer{{Host: "b1", Port: 81}},
+		Labels:  map[string]string{"env": "prod"},
+		Limits:  map[string]tConfServer{"eu": {Host: "eu", Port: 82}},
+		Timeout: 30 * time.Second,
+		IP:      net.ParseIP("10.0.0.1"),
+		Key:     []byte("secret"),
+	}
+
+	exp := map[string]string{
+		"server.host":     "localhost",
+		"server.port":     "80",
+		"backups[0].host": "b1",
+		"backups[0].port": "81",
+		"labels[env]":     "prod",
+		"limits[eu].host": "eu",
+		"limits[eu].port": "82",
+		"timeout":         "30s",
+		"ip":              "10.0.0.1",
+		"key":             "c2VjcmV0",
+	}
+
+	if f := Flatten(&c); reflect.DeepEqual(f, exp) == false {
+		t.Fatalf("Incorrect flattened config: %v", f)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+
+	type tConfDB struct {
+		User     string `conf:"user"`
+		Password string `conf:"password" conf_extraopts:"secret"`
+	}
+
+	type tConfOut struct {
+		Host string            `conf:"host"`
+		DB   tConfDB           `conf:"db"`
+		Keys map[string]string `conf:"keys" conf_extraopts:"secret"`
+	}
+
+	c := tConfOut{
+		Host: "localhost",
+		DB:   tConfDB{User: "app", Password: "pass"},
+		Keys: map[string]string{"api": "key"},
+	}
+
+	exp := "db.password=****\ndb.user=app\nhost=localhost\nkeys=****\n"
+
+	if r := Redacted(&c); r != exp {
+		t.Fatalf("Incorrect redacted config: %q", r)
+	}
+}
+
+type tRedactedStore interface {
+	Kind() string
+}
+
+type tRedactedS3 struct {
+	Key    string `conf:"key" conf_extraopts:"secret"`
+	Bucket string `conf:"bucket"`
+}
+
+func (s *tRedactedS3) Kind() string {
+	return "s3"
+}
+
+func TestRedactedPolymorphic(t *testing.T) {
+
+	RegisterType("redacted_s3", &tRedactedS3{})
+
+	type tConfOut struct {
+		Store tRedactedStore `conf:"store" conf_extraopts:"discriminator=type"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "store:\n  type: redacted_s3\n  key: TOPSECRET\n  bucket: b\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	exp := "store.bucket=b\nstore.key=****\n"
+
+	if r := Redacted(&c); r != exp {
+		t.Fatalf("Incorrect redacted config: %q", r)
+	}
+}
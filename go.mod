@@ -1,6 +1,6 @@
 module github.com/nixys/nxs-go-conf
 
-go 1.13
+go 1.16
 
 require (
 	github.com/mitchellh/mapstructure v1.1.2
@@ -0,0 +1,198 @@
+package conf
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+const (
+	// includeKeyDefault is a default config file top-level key with list of files to be included
+	includeKeyDefault = "include"
+
+	// includeKeyDisabled is an `IncludeKey` settings field value disabling includes
+	includeKeyDisabled = "-"
+)
+
+// includesCheck checks includes are enabled for config `conf`. Includes are disabled by `IncludeKey` settings field,
+// or if config struct has a top-level option with the same name as include key, so the key is decoded as a regular option
+func (s *Settings) includesCheck(conf interface{}) bool {
+
+	key := s.includeKey()
+	if key == includeKeyDisabled {
+		return false
+	}
+
+	// Top-level options of config file are options of config struct only if whole config is loaded
+	if s.RootKey != "" {
+		return true
+	}
+
+	t := reflect.TypeOf(conf)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+
+	names := make(map[string]bool)
+	s.structOptNames(t, names)
+
+	return names[strings.ToLower(key)] == false
+}
+
+// includeKey returns config file top-level key with list of files to be included
+func (s *Settings) includeKey() string {
+
+	if s.IncludeKey == "" {
+		return includeKeyDefault
+	}
+
+	return s.IncludeKey
+}
+
+// rawIncludesResolve loads files listed in include key of config file `name` raw map `rawConf`
+// and deep-merges `rawConf` over them. Included files may include other files, `chain` contains
+// files currently being included to detect cycles
+func (s *Settings) rawIncludesResolve(rawConf map[string]interface{}, name string, chain []string) (map[string]interface{}, error) {
+
+	key := s.includeKey()
+
+	v, ok := rawConf[key]
+	if ok == false {
+		return rawConf, nil
+	}
+
+	delete(rawConf, key)
+
+	var incs []string
+
+	switch i := v.(type) {
+	case string:
+		incs = []string{i}
+	case []interface{}:
+		for _, e := range i {
+			str, ok := e.(string)
+			if ok == false {
+				return nil, fmt.Errorf("file '%s': `%s` must be a list of files names", name, key)
+			}
+			incs = append(incs, str)
+		}
+	default:
+		return nil, fmt.Errorf("file '%s': `%s` must be a list of files names", name, key)
+	}
+
+	var base interface{} = make(map[string]interface{})
+
+	for _, inc := range incs {
+
+		p := s.includePath(name, inc)
+
+		for _, c := range chain {
+			if c == p {
+				return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(chain, " -> "), p)
+			}
+		}
+
+		data, err := s.includeRead(p)
+		if err != nil {
+			return nil, fmt.Errorf("file '%s' include error: %v", name, err)
+		}
+
+		t, err := confTypeDetect(p)
+		if err != nil {
+			t = s.ConfType
+		}
+
+		r, err := s.rawUnmarshal(data, t)
+		if err != nil {
+			return nil, fmt.Errorf("file '%s': %v", p, err)
+		}
+
+		r, err = s.rawIncludesResolve(r, p, append(chain, p))
+		if err != nil {
+			return nil, err
+		}
+
+		base = s.rawMerge(base, r)
+	}
+
+	return s.rawMerge(base, rawConf).(map[string]interface{}), nil
+}
+
+// includePath returns path of included file `inc` relative to the directory of including file `name`.
+// If `inc` is empty, cleaned `name` is returned
+func (s *Settings) includePath(name, inc string) string {
+
+	if s.includeFS != nil {
+		if inc == "" {
+			return path.Clean(name)
+		}
+		return path.Join(path.Dir(name), inc)
+	}
+
+	if inc == "" {
+		return filepath.Clean(name)
+	}
+
+	if filepath.IsAbs(inc) == true {
+		return filepath.Clean(inc)
+	}
+
+	return filepath.Join(filepath.Dir(name), inc)
+}
+
+// includeRead reads included file `name`
+func (s *Settings) includeRead(name string) ([]byte, error) {
+
+	if s.includeFS != nil {
+		return fs.ReadFile(s.includeFS, name)
+	}
+
+	return ioutil.ReadFile(name)
+}
+
+// rawMerge deep-merges raw value `over` over raw value `base`. Maps are merged by keys,
+// other values (including slices) of `over` replace values of `base`
+func (s *Settings) rawMerge(base, over interface{}) interface{} {
+
+	b := reflect.ValueOf(base)
+	o := reflect.ValueOf(over)
+
+	if b.Kind() != reflect.Map || o.Kind() != reflect.Map {
+		return over
+	}
+
+	for _, k := range o.MapKeys() {
+
+		key := k
+		if key.Kind() == reflect.Interface {
+			key = key.Elem()
+		}
+
+		// Maps of different key types (e.g. from files of different formats) can't be merged
+		if key.Type().AssignableTo(b.Type().Key()) == false {
+			return over
+		}
+
+		v := o.MapIndex(k).Interface()
+
+		if e := b.MapIndex(key); e.IsValid() == true {
+			v = s.rawMerge(e.Interface(), v)
+		}
+
+		if v == nil {
+			b.SetMapIndex(key, reflect.Zero(b.Type().Elem()))
+		} else {
+			b.SetMapIndex(key, reflect.ValueOf(v))
+		}
+	}
+
+	return base
+}
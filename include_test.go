@@ -0,0 +1,139 @@
+package conf
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testIncludeFiles(t *testing.T, files map[string]string) string {
+
+	dir := t.TempDir()
+
+	for n, d := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, n), []byte(d), 0644); err != nil {
+			t.Fatal("Config file prepare error:", err)
+		}
+	}
+
+	return dir
+}
+
+func TestInclude(t *testing.T) {
+
+	type tConfServer struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port"`
+	}
+
+	type tConfOut struct {
+		Name   string      `conf:"name"`
+		Server tConfServer `conf:"server"`
+	}
+
+	dir := testIncludeFiles(t, map[string]string{
+		"base.yaml": "name: base\nserver:\n  host: localhost\n  port: 80\n",
+		"main.yaml": "include: [\"base.yaml\"]\nserver:\n  port: 8080\n",
+	})
+
+	var c tConfOut
+
+	if err := Load(&c, Settings{ConfPath: filepath.Join(dir, "main.yaml"), ConfType: ConfigTypeYAML, UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "base" || c.Server.Host != "localhost" || c.Server.Port != 8080 {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	var f tConfOut
+
+	fsys := fstest.MapFS{
+		"conf/base.json": {Data: []byte("{\"name\": \"base\", \"server\": {\"host\": \"localhost\"}}")},
+		"conf/main.yaml": {Data: []byte("include: base.json\nname: main\n")},
+	}
+
+	if err := LoadFS(&f, fsys, "conf/main.yaml", Settings{ConfType: ConfigTypeAuto}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if f.Name != "main" || f.Server.Host != "localhost" {
+		t.Fatal("Incorrect loaded data")
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name"`
+	}
+
+	dir := testIncludeFiles(t, map[string]string{
+		"a.yaml":    "include: [\"b.yaml\"]\n",
+		"b.yaml":    "include: [\"a.yaml\"]\n",
+		"main.yaml": "include: [\"missing.yaml\"]\n",
+	})
+
+	err := Load(&tConfOut{}, Settings{ConfPath: filepath.Join(dir, "a.yaml"), ConfType: ConfigTypeYAML})
+	if err == nil || strings.Contains(err.Error(), "include cycle") == false {
+		t.Fatal("Expected include cycle error, got:", err)
+	}
+
+	err = Load(&tConfOut{}, Settings{ConfPath: filepath.Join(dir, "main.yaml"), ConfType: ConfigTypeYAML})
+	if err == nil || strings.Contains(err.Error(), "missing.yaml") == false {
+		t.Fatal("Expected missing include error, got:", err)
+	}
+}
+
+func TestIncludeKey(t *testing.T) {
+
+	dir := testIncludeFiles(t, map[string]string{
+		"base.yaml": "name: base\n",
+		"main.yaml": "include: [\"base.yaml\"]\n",
+		"alt.yaml":  "imports: [\"base.yaml\"]\ninclude: [\"a\", \"b\"]\n",
+	})
+
+	// Option named as include key is decoded as a regular option
+	type tConfInclude struct {
+		Name    string   `conf:"name"`
+		Include []string `conf:"include"`
+	}
+
+	var c tConfInclude
+
+	if err := Load(&c, Settings{ConfPath: filepath.Join(dir, "main.yaml"), ConfType: ConfigTypeYAML, UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "" || len(c.Include) != 1 || c.Include[0] != "base.yaml" {
+		t.Fatal("Incorrect loaded data:", c)
+	}
+
+	// Include key may be changed
+	var a tConfInclude
+
+	if err := Load(&a, Settings{ConfPath: filepath.Join(dir, "alt.yaml"), ConfType: ConfigTypeYAML, IncludeKey: "imports"}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if a.Name != "base" || len(a.Include) != 2 {
+		t.Fatal("Incorrect loaded data:", a)
+	}
+
+	// Includes may be disabled
+	type tConfOut struct {
+		Name string `conf:"name"`
+	}
+
+	var d tConfOut
+
+	if err := Load(&d, Settings{ConfPath: filepath.Join(dir, "main.yaml"), ConfType: ConfigTypeYAML, IncludeKey: "-"}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if d.Name != "" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+}
@@ -0,0 +1,79 @@
+package conf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// iniUnmarshal parses INI config data into raw map.
+// Keys before any section go to the root, `[section]` blocks become nested maps keyed by the section name.
+// Lines started with `;` or `#` are comments. Values may be enclosed in single or double quotes.
+// If a key is repeated within a section the last value is used.
+func iniUnmarshal(data []byte) (map[string]interface{}, error) {
+
+	rawConf := make(map[string]interface{})
+	section := rawConf
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for n := 1; scanner.Scan() == true; n++ {
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, ";") == true || strings.HasPrefix(line, "#") == true {
+			continue
+		}
+
+		// Section header
+		if strings.HasPrefix(line, "[") == true {
+
+			if strings.HasSuffix(line, "]") == false {
+				return nil, fmt.Errorf("ini: line %d: unterminated section header", n)
+			}
+
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if name == "" {
+				return nil, fmt.Errorf("ini: line %d: empty section name", n)
+			}
+
+			if s, ok := rawConf[name].(map[string]interface{}); ok == true {
+				section = s
+			} else {
+				section = make(map[string]interface{})
+				rawConf[name] = section
+			}
+
+			continue
+		}
+
+		p := strings.SplitN(line, "=", 2)
+		if len(p) != 2 {
+			return nil, fmt.Errorf("ini: line %d: expected `key = value`", n)
+		}
+
+		key := strings.TrimSpace(p[0])
+		if key == "" {
+			return nil, fmt.Errorf("ini: line %d: empty key", n)
+		}
+
+		section[key] = iniValueUnquote(strings.TrimSpace(p[1]))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ini: %v", err)
+	}
+
+	return rawConf, nil
+}
+
+// iniValueUnquote removes surrounding single or double quotes from value `v`
+func iniValueUnquote(v string) string {
+
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+
+	return v
+}
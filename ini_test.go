@@ -0,0 +1,94 @@
+package conf
+
+import (
+	"testing"
+)
+
+func TestINIFormat(t *testing.T) {
+
+	type tConfOut struct {
+		Name     string `conf:"name" conf_extraopts:"required"`
+		Debug    bool   `conf:"debug"`
+		Database struct {
+			Host string `conf:"host" conf_extraopts:"required"`
+			Port int    `conf:"port" conf_extraopts:"default=5432"`
+			User string `conf:"user"`
+		} `conf:"database" conf_extraopts:"required"`
+	}
+
+	d := `
+; Global options
+name = "Test App"
+debug = true
+
+[database]
+# Connection options
+host = localhost
+user = 'admin'
+`
+
+	var c tConfOut
+
+	if err := LoadBytes(&c, []byte(d), Settings{
+		ConfType:    ConfigTypeINI,
+		UnknownDeny: true,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "Test App" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	if c.Debug != true {
+		t.Fatal("Incorrect loaded data: Debug")
+	}
+
+	if c.Database.Host != "localhost" || c.Database.Port != 5432 || c.Database.User != "admin" {
+		t.Fatal("Incorrect loaded data: Database")
+	}
+
+	// Check malformed data
+	if err := LoadBytes(&c, []byte("[database\nhost = localhost\n"), Settings{
+		ConfType: ConfigTypeINI,
+	}); err == nil {
+		t.Fatal("Expected error for malformed INI data")
+	}
+}
+
+func TestININestedDelimiter(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name"`
+		DB   struct {
+			Host    string `conf:"host"`
+			Port    int    `conf:"port"`
+			Primary struct {
+				Weight int `conf:"weight"`
+			} `conf:"primary"`
+		} `conf:"db"`
+	}
+
+	d := `
+name = app
+db__host = localhost
+db__primary__weight = 10
+
+[db]
+port = 5432
+`
+
+	var c tConfOut
+
+	if err := LoadBytes(&c, []byte(d), Settings{ConfType: ConfigTypeINI, NestedDelimiter: "__", UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "app" || c.DB.Host != "localhost" || c.DB.Port != 5432 || c.DB.Primary.Weight != 10 {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	if err := LoadBytes(&c, []byte("db = a\ndb__host = b\n"), Settings{ConfType: ConfigTypeINI, NestedDelimiter: "__"}); err == nil {
+		t.Fatal("Expected error for conflicting keys")
+	}
+}
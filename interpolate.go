@@ -0,0 +1,165 @@
+package conf
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+// interpolateRegexp matches references to other options within string options values (e.g. `${base_dir}`)
+var interpolateRegexp = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// interpolateOpts substitutes references `${dotted.path}` within string options values (including strings within
+// slices and maps) with values of referenced options. Referenced string options are resolved first, so references
+// may be chained regardless of options order
+func (s *Settings) interpolateOpts(val reflect.Value) error {
+
+	return s.walkFields(val, "", func(vf reflect.Value, tf reflect.StructField, elName string) error {
+		return s.interpolateValue(val, vf, elName)
+	})
+}
+
+// interpolateValue substitutes references within string value `vf` with path `name`, or within string elements
+// of slice or map `vf`. Structs are skipped, since its fields are walked separately
+func (s *Settings) interpolateValue(root reflect.Value, vf reflect.Value, name string) error {
+
+	for (vf.Kind() == reflect.Ptr || vf.Kind() == reflect.Interface) && vf.IsNil() == false {
+		vf = vf.Elem()
+	}
+
+	switch vf.Kind() {
+	case reflect.String:
+		if vf.CanSet() == false {
+			return nil
+		}
+
+		str, err := s.interpolate(root, vf.String(), name, map[string]bool{name: true})
+		if err != nil {
+			return err
+		}
+
+		vf.SetString(str)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < vf.Len(); i++ {
+			if err := s.interpolateValue(root, vf.Index(i), fmt.Sprintf("%s[%d]", name, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range vf.MapKeys() {
+			e := vf.MapIndex(k)
+			if e.Kind() == reflect.Struct {
+				continue
+			}
+
+			// Create copy of element to make it writable. Values within interfaces are copied as well
+			t := reflect.Indirect(reflect.New(e.Type()))
+			t.Set(e)
+			if e.Kind() == reflect.Interface && e.IsNil() == false && e.Elem().Kind() == reflect.String {
+				t = reflect.Indirect(reflect.New(e.Elem().Type()))
+				t.Set(e.Elem())
+			}
+
+			if err := s.interpolateValue(root, t, fmt.Sprintf("%s[%v]", name, k)); err != nil {
+				return err
+			}
+
+			vf.SetMapIndex(k, t)
+		}
+	}
+
+	return nil
+}
+
+// interpolate returns `str` of option `name` with references to options of struct `root` substituted.
+// `resolving` contains paths of options being resolved to detect cyclic references
+func (s *Settings) interpolate(root reflect.Value, str string, name string, resolving map[string]bool) (string, error) {
+
+	var rErr error
+
+	r := interpolateRegexp.ReplaceAllStringFunc(str, func(ref string) string {
+
+		if rErr != nil {
+			return ref
+		}
+
+		path := interpolateRegexp.FindStringSubmatch(ref)[1]
+
+		if resolving[path] == true {
+			rErr = fmt.Errorf("option '%s' has cyclic reference to option '%s'", name, path)
+			return ref
+		}
+
+		v, ok := s.valueByPath(root, path)
+		if ok == false {
+			rErr = fmt.Errorf("option '%s' refers to unknown option '%s'", name, path)
+			return ref
+		}
+
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() == true {
+				rErr = fmt.Errorf("option '%s' refers to unset option '%s'", name, path)
+				return ref
+			}
+			v = v.Elem()
+		}
+
+		if v.Kind() != reflect.String {
+			str, err := s.interpolateFormat(v)
+			if err != nil {
+				rErr = fmt.Errorf("option '%s' refers to option '%s': %v", name, path, err)
+			}
+			return str
+		}
+
+		// Referenced string option is resolved and kept resolved, so it's not resolved again
+		resolving[path] = true
+		str, err := s.interpolate(root, v.String(), path, resolving)
+		delete(resolving, path)
+
+		if err != nil {
+			rErr = err
+			return ref
+		}
+
+		if v.CanSet() == true {
+			v.SetString(str)
+		}
+
+		return str
+	})
+
+	if rErr != nil {
+		return str, rErr
+	}
+
+	return r, nil
+}
+
+// interpolateFormat returns string representation of scalar option value `v` to be substituted into other options
+func (s *Settings) interpolateFormat(v reflect.Value) (string, error) {
+
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok == true {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+
+	if m, ok := v.Interface().(fmt.Stringer); ok == true {
+		return m.String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+
+	return "", fmt.Errorf("value of type `%s` can't be interpolated", v.Type())
+}
@@ -0,0 +1,155 @@
+package conf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterpolate(t *testing.T) {
+
+	type tConfOut struct {
+		LogDir  string        `conf:"log_dir"`
+		BaseDir string        `conf:"base_dir" conf_extraopts:"default=/var/lib/app"`
+		Listen  string        `conf:"listen"`
+		Port    int           `conf:"port"`
+		Timeout time.Duration `conf:"timeout"`
+		Message string        `conf:"message"`
+	}
+
+	var c tConfOut
+
+	data := "log_dir: ${base_dir}/logs\nlisten: \"0.0.0.0:${port}\"\nport: 8080\ntimeout: 5s\nmessage: timeout is ${timeout}\n"
+
+	if err := testLoadYAML(t, data, &c, Settings{Interpolate: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	// References are resolved against default values too
+	if c.LogDir != "/var/lib/app/logs" {
+		t.Fatal("Incorrect loaded data: LogDir:", c.LogDir)
+	}
+
+	if c.Listen != "0.0.0.0:8080" {
+		t.Fatal("Incorrect loaded data: Listen:", c.Listen)
+	}
+
+	if c.Message != "timeout is 5s" {
+		t.Fatal("Incorrect loaded data: Message:", c.Message)
+	}
+
+	// References are kept as is without the setting
+	var d tConfOut
+
+	if err := testLoadYAML(t, data, &d, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if d.LogDir != "${base_dir}/logs" {
+		t.Fatal("Incorrect loaded data: LogDir:", d.LogDir)
+	}
+}
+
+func TestInterpolateChain(t *testing.T) {
+
+	type tPaths struct {
+		Data string `conf:"data"`
+		Logs string `conf:"logs"`
+	}
+
+	type tConfOut struct {
+		Paths tPaths `conf:"paths"`
+		Root  string `conf:"root"`
+		Base  string `conf:"base"`
+	}
+
+	var c tConfOut
+
+	// Options refer to options specified later in config
+	data := "paths:\n  data: ${base}/data\n  logs: ${paths.data}/logs\nroot: /srv\nbase: ${root}/app\n"
+
+	if err := testLoadYAML(t, data, &c, Settings{Interpolate: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Base != "/srv/app" {
+		t.Fatal("Incorrect loaded data: Base:", c.Base)
+	}
+
+	if c.Paths.Data != "/srv/app/data" {
+		t.Fatal("Incorrect loaded data: Paths.Data:", c.Paths.Data)
+	}
+
+	if c.Paths.Logs != "/srv/app/data/logs" {
+		t.Fatal("Incorrect loaded data: Paths.Logs:", c.Paths.Logs)
+	}
+}
+
+func TestInterpolateCollections(t *testing.T) {
+
+	type tConfOut struct {
+		Base   string                 `conf:"base"`
+		Dirs   []string               `conf:"dirs"`
+		Paths  map[string]string      `conf:"paths"`
+		Extra  map[string]interface{} `conf:"extra"`
+		Groups []struct {
+			Dir string `conf:"dir"`
+		} `conf:"groups"`
+	}
+
+	var c tConfOut
+
+	data := "base: /srv\ndirs: ['${base}/a']\npaths: {k: '${base}/b'}\nextra: {k: '${base}/c', num: 1}\ngroups: [{dir: '${base}/d'}]\n"
+
+	if err := testLoadYAML(t, data, &c, Settings{Interpolate: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if len(c.Dirs) != 1 || c.Dirs[0] != "/srv/a" {
+		t.Fatal("Incorrect loaded data: Dirs:", c.Dirs)
+	}
+
+	if c.Paths["k"] != "/srv/b" {
+		t.Fatal("Incorrect loaded data: Paths:", c.Paths)
+	}
+
+	if c.Extra["k"] != "/srv/c" || c.Extra["num"] != 1 {
+		t.Fatal("Incorrect loaded data: Extra:", c.Extra)
+	}
+
+	if c.Groups[0].Dir != "/srv/d" {
+		t.Fatal("Incorrect loaded data: Groups")
+	}
+
+	// Errors are reported with paths of elements
+	err := testLoadYAML(t, "dirs: [a, '${missing}']\n", &c, Settings{Interpolate: true})
+	if err == nil || strings.Contains(err.Error(), "option 'dirs[1]' refers to unknown option 'missing'") == false {
+		t.Fatal("Expected unknown reference error, got:", err)
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+
+	type tConfOut struct {
+		A string `conf:"a"`
+		B string `conf:"b"`
+		C string `conf:"c"`
+	}
+
+	var c tConfOut
+
+	err := testLoadYAML(t, "a: x${b}\nb: ${c}\nc: ${a}\n", &c, Settings{Interpolate: true})
+	if err == nil || strings.Contains(err.Error(), "cyclic reference") == false {
+		t.Fatal("Expected cyclic reference error, got:", err)
+	}
+
+	err = testLoadYAML(t, "a: ${a}\n", &c, Settings{Interpolate: true})
+	if err == nil || strings.Contains(err.Error(), "option 'a' has cyclic reference to option 'a'") == false {
+		t.Fatal("Expected self reference error, got:", err)
+	}
+
+	err = testLoadYAML(t, "a: ${d.e}\n", &c, Settings{Interpolate: true})
+	if err == nil || strings.Contains(err.Error(), "option 'a' refers to unknown option 'd.e'") == false {
+		t.Fatal("Expected unknown reference error, got:", err)
+	}
+}
@@ -0,0 +1,90 @@
+package conf
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// jsonCommentsStrip removes `//` and `/* */` comments and trailing commas before closing
+// brackets and braces from JSON data. Newlines of comments are kept to preserve lines numbers
+func jsonCommentsStrip(data []byte) ([]byte, error) {
+
+	var b bytes.Buffer
+
+	// Remove comments
+	for i := 0; i < len(data); i++ {
+		switch {
+		case data[i] == '"':
+			j, err := jsonStringEnd(data, i)
+			if err != nil {
+				return nil, err
+			}
+			b.Write(data[i : j+1])
+			i = j
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				b.WriteByte('\n')
+			}
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '*':
+			j := bytes.Index(data[i+2:], []byte("*/"))
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			for _, c := range data[i : i+2+j+2] {
+				if c == '\n' {
+					b.WriteByte('\n')
+				}
+			}
+			i += 2 + j + 1
+		default:
+			b.WriteByte(data[i])
+		}
+	}
+
+	data = b.Bytes()
+	r := make([]byte, 0, len(data))
+
+	// Remove trailing commas
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			j, err := jsonStringEnd(data, i)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, data[i:j+1]...)
+			i = j
+		case ',':
+			j := i + 1
+			for j < len(data) && bytes.IndexByte([]byte(" \t\r\n"), data[j]) >= 0 {
+				j++
+			}
+			if j < len(data) && (data[j] == '}' || data[j] == ']') {
+				continue
+			}
+			r = append(r, data[i])
+		default:
+			r = append(r, data[i])
+		}
+	}
+
+	return r, nil
+}
+
+// jsonStringEnd returns index of closing quote of JSON string started at index `start`
+func jsonStringEnd(data []byte, start int) (int, error) {
+
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unterminated string")
+}
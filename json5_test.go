@@ -0,0 +1,44 @@
+package conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSON5(t *testing.T) {
+
+	type tConfOut struct {
+		Host  string   `conf:"host"`
+		URL   string   `conf:"url"`
+		Ports []int    `conf:"ports"`
+		Tags  []string `conf:"tags"`
+	}
+
+	data := `{
+	// Server host
+	"host": "localhost", /* inline
+	comment */
+	"url": "http://example.com/*path*/", // not a comment within string
+	"ports": [80, 443,],
+	"tags": ["a,", "b"],
+}
+`
+
+	var c tConfOut
+
+	if err := LoadBytes(&c, []byte(data), Settings{ConfType: ConfigTypeJSON5}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Host != "localhost" || c.URL != "http://example.com/*path*/" {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	if reflect.DeepEqual(c.Ports, []int{80, 443}) == false || reflect.DeepEqual(c.Tags, []string{"a,", "b"}) == false {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	if err := LoadBytes(&c, []byte("{\"host\": \"a\" /* unterminated"), Settings{ConfType: ConfigTypeJSON5}); err == nil {
+		t.Fatal("Expected error for unterminated comment")
+	}
+}
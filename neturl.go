@@ -0,0 +1,57 @@
+package conf
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+var urlType = reflect.TypeOf(url.URL{})
+
+// urlText makes `url.URL` options decodable from strings the same way as options implementing `encoding.TextUnmarshaler`
+type urlText struct {
+	u *url.URL
+}
+
+func (t urlText) UnmarshalText(text []byte) error {
+
+	u, err := url.Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	*t.u = *u
+
+	return nil
+}
+
+// checkSchemes checks that scheme of URL option `val` is one of space-separated schemes `schemes`
+func (s *Settings) checkSchemes(val reflect.Value, elName string, schemes string) error {
+
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() == true {
+			return nil
+		}
+		val = val.Elem()
+	}
+
+	if val.Type() != urlType {
+		return fmt.Errorf("option '%s' with `%s` must be a URL", elName, tagConfSchemesName)
+	}
+
+	u := val.Addr().Interface().(*url.URL)
+
+	// Options not specified in config file have empty URL
+	if *u == (url.URL{}) {
+		return nil
+	}
+
+	for _, e := range strings.Fields(schemes) {
+		if strings.EqualFold(u.Scheme, e) == true {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("option '%s' URL scheme '%s' is not allowed, must be one of: %s", elName, u.Scheme, strings.Join(strings.Fields(schemes), ", "))
+}
@@ -0,0 +1,46 @@
+package conf
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+
+	type tConfOut struct {
+		Endpoint *url.URL `conf:"endpoint"`
+		Proxy    url.URL  `conf:"proxy" conf_extraopts:"default=http://proxy.local:3128"`
+		Callback url.URL  `conf:"callback" conf_extraopts:"schemes=https"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "endpoint: https://example.com/api\ncallback: https://example.com/cb\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Endpoint == nil || c.Endpoint.Host != "example.com" || c.Endpoint.Path != "/api" {
+		t.Fatal("Incorrect loaded data: Endpoint")
+	}
+
+	if c.Proxy.Host != "proxy.local:3128" {
+		t.Fatal("Incorrect loaded data: Proxy")
+	}
+
+	if Flatten(&c)["callback"] != "https://example.com/cb" {
+		t.Fatal("Incorrect flatten data:", Flatten(&c))
+	}
+
+	// Check invalid URL
+	err := testLoadYAML(t, "endpoint: http://example.com:port\n", &tConfOut{}, Settings{})
+	if err == nil || strings.Contains(err.Error(), "invalid port") == false {
+		t.Fatal("Incorrect invalid URL error:", err)
+	}
+
+	// Check scheme restriction
+	err = testLoadYAML(t, "callback: http://example.com/cb\n", &tConfOut{}, Settings{})
+	if err == nil || strings.Contains(err.Error(), "option 'callback' URL scheme 'http' is not allowed") == false {
+		t.Fatal("Incorrect URL scheme error:", err)
+	}
+}
@@ -0,0 +1,208 @@
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// ErrParse is an error of config data parsing with location of the error.
+// Use `errors.As` to get it from the error returned by `Load`
+type ErrParse struct {
+
+	// Line is a number of line (starting from 1) the error occurred at, or 0 if unknown
+	Line int
+
+	// Column is a number of column (starting from 1) the error occurred at, or 0 if unknown
+	Column int
+
+	// Err is an underlying parser error
+	Err error
+}
+
+func (e *ErrParse) Error() string {
+
+	// YAML parser errors already contain line numbers
+	if e.Column == 0 {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *ErrParse) Unwrap() error {
+	return e.Err
+}
+
+var yamlErrLineRegexp = regexp.MustCompile(`line (\d+)`)
+
+// yamlParseError wraps YAML parser error `err` into `ErrParse` with line number taken from the error message
+func yamlParseError(err error) error {
+
+	e := &ErrParse{
+		Err: err,
+	}
+
+	if m := yamlErrLineRegexp.FindStringSubmatch(err.Error()); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+	}
+
+	return e
+}
+
+// jsonParseError wraps JSON parser error `err` into `ErrParse` with line and column calculated from
+// the error offset within `data`
+func jsonParseError(data []byte, err error) error {
+
+	var offset int64
+
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return &ErrParse{
+			Err: err,
+		}
+	}
+
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	e := &ErrParse{
+		Line:   1,
+		Column: 1,
+		Err:    err,
+	}
+
+	for _, c := range data[:offset] {
+		if c == '\n' {
+			e.Line++
+			e.Column = 1
+		} else {
+			e.Column++
+		}
+	}
+
+	return e
+}
+
+// ErrType is an error of option value type mismatch (e.g. string is specified for int option).
+// Use `errors.As` to get it from the error returned by `Load`
+type ErrType struct {
+
+	// Path is a path of the option
+	Path string
+
+	// Expected is a type of the option
+	Expected string
+
+	// Got is a type of the value specified in config
+	Got string
+
+	// Value is the value specified in config if it's a string, or empty otherwise
+	Value string
+}
+
+func (e *ErrType) Error() string {
+
+	if e.Value != "" {
+		return fmt.Sprintf("option '%s' expected type '%s', got '%s' value '%s'", e.Path, e.Expected, e.Got, e.Value)
+	}
+
+	return fmt.Sprintf("option '%s' expected type '%s', got '%s'", e.Path, e.Expected, e.Got)
+}
+
+// decodeErrors contains errors of options decoding. Type mismatch errors are `ErrType`, other errors are kept
+// as reported by the decoder
+type decodeErrors []error
+
+// Error formats errors the same way as the decoder does
+func (e decodeErrors) Error() string {
+
+	var l []string
+	for _, err := range e {
+		l = append(l, fmt.Sprintf("* %s", err))
+	}
+
+	sort.Strings(l)
+
+	return fmt.Sprintf("%d error(s) decoding:\n\n%s", len(e), strings.Join(l, "\n"))
+}
+
+// Unwrap returns the first type mismatch error, so it may be got with `errors.As`
+func (e decodeErrors) Unwrap() error {
+
+	for _, err := range e {
+		if _, ok := err.(*ErrType); ok == true {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var (
+	typeErrRegexp     = regexp.MustCompile(`^'(.*)' expected type '(.*)', got unconvertible type '(.*)'`)
+	typeHookErrRegexp = regexp.MustCompile(`^error decoding '(.*)': expected type '(.*)', got unconvertible type '(.*)' value '(.*)'`)
+)
+
+// decodeErrorConvert converts type mismatch errors within mapstructure decoding error `err` into `ErrType`.
+// Other errors are kept as is
+func decodeErrorConvert(err error) error {
+
+	var me *mapstructure.Error
+
+	if errors.As(err, &me) == false {
+		return err
+	}
+
+	var r decodeErrors
+
+	for _, e := range me.Errors {
+
+		if m := typeErrRegexp.FindStringSubmatch(e); m != nil {
+			r = append(r, &ErrType{
+				Path:     m[1],
+				Expected: m[2],
+				Got:      m[3],
+			})
+			continue
+		}
+
+		if m := typeHookErrRegexp.FindStringSubmatch(e); m != nil {
+			r = append(r, &ErrType{
+				Path:     m[1],
+				Expected: m[2],
+				Got:      m[3],
+				Value:    m[4],
+			})
+			continue
+		}
+
+		r = append(r, errors.New(e))
+	}
+
+	return r
+}
+
+// typeErrorCheck checks error `err` of conversion from string is caused by the string format
+// (e.g. `abc` for int option) rather than by the value (e.g. out of range)
+func typeErrorCheck(err error) bool {
+
+	var ne *strconv.NumError
+
+	if errors.As(err, &ne) == true {
+		return ne.Err == strconv.ErrSyntax
+	}
+
+	return strings.HasPrefix(err.Error(), "time: invalid duration")
+}
@@ -0,0 +1,121 @@
+package conf
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrParseYAML(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name"`
+	}
+
+	var c tConfOut
+
+	err := testLoadYAML(t, "name: test\nlist:\n  - a\n  b: c\n", &c, Settings{})
+	if err == nil {
+		t.Fatal("Expected parse error")
+	}
+
+	var e *ErrParse
+	if errors.As(err, &e) == false {
+		t.Fatal("Error is not ErrParse:", err)
+	}
+
+	if e.Line == 0 {
+		t.Fatal("Incorrect error line:", err)
+	}
+}
+
+func TestErrParseJSON(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name"`
+	}
+
+	var c tConfOut
+
+	p := filepath.Join(t.TempDir(), "conf.json")
+	if err := ioutil.WriteFile(p, []byte("{\n  \"name\": \"test\",\n  \"port\" 80\n}\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	err := Load(&c, Settings{
+		ConfPath: p,
+		ConfType: ConfigTypeJSON,
+	})
+	if err == nil {
+		t.Fatal("Expected parse error")
+	}
+
+	var e *ErrParse
+	if errors.As(err, &e) == false {
+		t.Fatal("Error is not ErrParse:", err)
+	}
+
+	if e.Line != 3 {
+		t.Fatal("Incorrect error line:", e.Line, err)
+	}
+
+	if e.Column == 0 {
+		t.Fatal("Incorrect error column:", err)
+	}
+}
+
+func TestErrType(t *testing.T) {
+
+	type tConfOut struct {
+		Port     int `conf:"port"`
+		Database struct {
+			Timeouts []int `conf:"timeouts"`
+		} `conf:"database"`
+	}
+
+	var c tConfOut
+
+	err := testLoadYAML(t, "port: abc\n", &c, Settings{})
+
+	var e *ErrType
+	if errors.As(err, &e) == false {
+		t.Fatal("Error is not ErrType:", err)
+	}
+
+	if e.Path != "port" || e.Expected != "int" || e.Got != "string" || e.Value != "abc" {
+		t.Fatalf("Incorrect type error: %+v", e)
+	}
+
+	// Nested option with non-string value among several errors
+	err = testLoadYAML(t, "port: [80]\ndatabase:\n  timeouts: [1, x]\n", &c, Settings{})
+
+	if errors.As(err, &e) == false {
+		t.Fatal("Error is not ErrType:", err)
+	}
+
+	if strings.Contains(err.Error(), "option 'database.timeouts[1]' expected type 'int', got 'string' value 'x'") == false {
+		t.Fatal("Incorrect type error:", err)
+	}
+
+	if strings.Contains(err.Error(), "option 'port' expected type 'int', got '[]interface {}'") == false {
+		t.Fatal("Incorrect type error:", err)
+	}
+
+	// Errors count is kept
+	if strings.Contains(err.Error(), "2 error(s) decoding:") == false {
+		t.Fatal("Incorrect errors count:", err)
+	}
+
+	// Other errors are kept as is
+	err = testLoadYAML(t, "port: ENV:TEST_ERR_TYPE_EMPTY\n", &c, Settings{})
+
+	if errors.As(err, &e) == true {
+		t.Fatal("Unexpected ErrType:", err)
+	}
+
+	if strings.Contains(err.Error(), "1 error(s) decoding:\n\n* error decoding 'port': empty ENV variable 'TEST_ERR_TYPE_EMPTY'") == false {
+		t.Fatal("Incorrect error:", err)
+	}
+}
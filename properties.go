@@ -0,0 +1,162 @@
+package conf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// propertiesUnmarshal parses Java-style properties config data into raw map.
+// Keys are separated from values by `=`, `:` or whitespace, dotted keys (e.g. `db.host`) become nested maps.
+// Lines started with `#` or `!` are comments. Line ended with `\` is continued on the next line.
+// If a key is repeated the last value is used.
+func propertiesUnmarshal(data []byte) (map[string]interface{}, error) {
+
+	rawConf := make(map[string]interface{})
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+
+		n := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+
+		if line == "" || strings.HasPrefix(line, "#") == true || strings.HasPrefix(line, "!") == true {
+			continue
+		}
+
+		// Join continued lines
+		for propertiesLineContinued(line) == true {
+			line = line[:len(line)-1]
+			if i+1 < len(lines) {
+				i++
+				line += strings.TrimLeft(lines[i], " \t\f")
+			}
+		}
+
+		k, v := propertiesLineSplit(line)
+
+		key, err := propertiesUnescape(k)
+		if err != nil {
+			return nil, fmt.Errorf("properties: line %d: %v", n, err)
+		}
+
+		value, err := propertiesUnescape(v)
+		if err != nil {
+			return nil, fmt.Errorf("properties: line %d: %v", n, err)
+		}
+
+		if err := propertiesKeySet(rawConf, key, value); err != nil {
+			return nil, fmt.Errorf("properties: line %d: %v", n, err)
+		}
+	}
+
+	return rawConf, nil
+}
+
+// propertiesLineContinued checks `line` ends with an odd number of backslashes
+func propertiesLineContinued(line string) bool {
+
+	c := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		c++
+	}
+
+	return c%2 == 1
+}
+
+// propertiesLineSplit splits `line` into escaped key and value
+func propertiesLineSplit(line string) (string, string) {
+
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return strings.TrimRight(line[:i], " \t\f"), strings.TrimLeft(line[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			v := strings.TrimLeft(line[i:], " \t\f")
+			if strings.HasPrefix(v, "=") == true || strings.HasPrefix(v, ":") == true {
+				v = strings.TrimLeft(v[1:], " \t\f")
+			}
+			return line[:i], v
+		}
+	}
+
+	return line, ""
+}
+
+// propertiesUnescape replaces escape sequences (including `\uXXXX`) in `s`
+func propertiesUnescape(s string) (string, error) {
+
+	if strings.Contains(s, "\\") == false {
+		return s, nil
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("malformed unicode escape")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("malformed unicode escape '\\u%s'", s[i+1:i+5])
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String(), nil
+}
+
+// propertiesKeySet sets `value` of dotted `key` in raw map `rawConf` creating nested maps
+func propertiesKeySet(rawConf map[string]interface{}, key, value string) error {
+
+	p := strings.Split(key, ".")
+	c := rawConf
+
+	for i, e := range p[:len(p)-1] {
+
+		if _, ok := c[e]; ok == false {
+			c[e] = make(map[string]interface{})
+		}
+
+		n, ok := c[e].(map[string]interface{})
+		if ok == false {
+			return fmt.Errorf("key '%s' conflicts with key '%s'", key, strings.Join(p[:i+1], "."))
+		}
+		c = n
+	}
+
+	l := p[len(p)-1]
+
+	if _, ok := c[l].(map[string]interface{}); ok == true {
+		return fmt.Errorf("key '%s' conflicts with nested keys", key)
+	}
+
+	c[l] = value
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package conf
+
+import (
+	"testing"
+)
+
+func TestPropertiesFormat(t *testing.T) {
+
+	type tConfOut struct {
+		Name     string `conf:"name" conf_extraopts:"required"`
+		Greeting string `conf:"greeting"`
+		Path     string `conf:"path"`
+		Database struct {
+			Host string `conf:"host" conf_extraopts:"required"`
+			Port int    `conf:"port" conf_extraopts:"default=5432"`
+			User string `conf:"user"`
+		} `conf:"database" conf_extraopts:"required"`
+	}
+
+	d := `
+# Global options
+name = Test App
+! Greeting is continued on the next line
+greeting: Hello, \
+          World \u00e9
+path C:\\data
+
+database.host=localhost
+database.user = admin
+`
+
+	var c tConfOut
+
+	if err := LoadBytes(&c, []byte(d), Settings{
+		ConfType:    ConfigTypeProperties,
+		UnknownDeny: true,
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "Test App" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	if c.Greeting != "Hello, World \u00e9" {
+		t.Fatal("Incorrect loaded data: Greeting:", c.Greeting)
+	}
+
+	if c.Path != "C:\\data" {
+		t.Fatal("Incorrect loaded data: Path:", c.Path)
+	}
+
+	if c.Database.Host != "localhost" || c.Database.Port != 5432 || c.Database.User != "admin" {
+		t.Fatal("Incorrect loaded data: Database")
+	}
+
+	// Check conflicting keys
+	if err := LoadBytes(&c, []byte("database = x\ndatabase.host = localhost\n"), Settings{
+		ConfType: ConfigTypeProperties,
+	}); err == nil {
+		t.Fatal("Expected error for conflicting keys")
+	}
+}
@@ -0,0 +1,45 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+)
+
+// LoadSplit reads config the same way as `Load`, but top-level options having no matching fields of `core`
+// are collected into `rest` as is (e.g. to be passed to a plugin) instead of being treated as unknown.
+// `UnknownDeny` settings field is still applied to nested options of `core`
+func LoadSplit(core interface{}, rest *map[string]interface{}, s Settings) error {
+
+	s.rest = make(map[string]interface{})
+
+	err := load(core, &s)
+
+	*rest = s.rest
+
+	return err
+}
+
+// restCollect moves top-level options within raw map `rawConf` that have no matching fields of struct `t` into `rest`
+func (s *Settings) restCollect(rawConf map[string]interface{}, t reflect.Type) {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	known := make(map[string]bool)
+	s.structOptNames(t, known)
+
+	for k, v := range rawConf {
+
+		if known[strings.ToLower(k)] == true {
+			continue
+		}
+
+		s.rest[k] = v
+		delete(rawConf, k)
+	}
+}
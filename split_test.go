@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadSplit(t *testing.T) {
+
+	type tConfOut struct {
+		Name     string `conf:"name" conf_extraopts:"required"`
+		Database struct {
+			Host string `conf:"host"`
+		} `conf:"database"`
+	}
+
+	p := filepath.Join(t.TempDir(), "conf.yml")
+	if err := ioutil.WriteFile(p, []byte("name: core\ndatabase:\n  host: localhost\nplugin_a:\n  enabled: true\nplugin_b: 5\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	var (
+		c    tConfOut
+		rest map[string]interface{}
+	)
+
+	if err := LoadSplit(&c, &rest, Settings{ConfPath: p, ConfType: ConfigTypeYAML, UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Name != "core" || c.Database.Host != "localhost" {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	e := map[string]interface{}{
+		"plugin_a": map[interface{}]interface{}{"enabled": true},
+		"plugin_b": 5,
+	}
+
+	if reflect.DeepEqual(rest, e) == false {
+		t.Fatal("Incorrect rest options:", rest)
+	}
+
+	// Nested unknown options are still denied
+	if err := ioutil.WriteFile(p, []byte("name: core\ndatabase:\n  port: 5432\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	err := LoadSplit(&c, &rest, Settings{ConfPath: p, ConfType: ConfigTypeYAML, UnknownDeny: true})
+	if err == nil || strings.Contains(err.Error(), "unknown option 'database.port'") == false {
+		t.Fatal("Incorrect unknown option error:", err)
+	}
+}
@@ -0,0 +1,62 @@
+package conf
+
+import (
+	"database/sql"
+	"encoding"
+	"reflect"
+)
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// scannerText makes options implementing `sql.Scanner` (e.g. `sql.NullString`) decodable from strings
+// the same way as options implementing `encoding.TextUnmarshaler`
+type scannerText struct {
+	sc sql.Scanner
+}
+
+func (t scannerText) UnmarshalText(text []byte) error {
+	return t.sc.Scan(string(text))
+}
+
+// scannerCheck checks that values of type `t` (or pointers to it) implement `sql.Scanner`
+func (s *Settings) scannerCheck(t reflect.Type) bool {
+
+	if t.Kind() == reflect.Ptr {
+		return t.Implements(scannerType)
+	}
+
+	return reflect.PtrTo(t).Implements(scannerType)
+}
+
+// decodeScanner decodes values into options implementing `sql.Scanner` (e.g. `sql.NullInt64`) with `Scan`,
+// so options specified in config file are valid. Strings are decoded with `UnmarshalText` if it's implemented. Values already decoded are kept as is
+func (s *Settings) decodeScanner(f reflect.Type, t reflect.Type, v interface{}) (interface{}, error) {
+
+	if v == nil || s.scannerCheck(f) == true || s.scannerCheck(t) == false {
+		return v, nil
+	}
+
+	tu := reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	if f.Kind() == reflect.String && (t.Implements(tu) == true || reflect.PtrTo(t).Implements(tu) == true) {
+		return v, nil
+	}
+
+	var r reflect.Value
+
+	if t.Kind() == reflect.Ptr {
+		r = reflect.New(t.Elem())
+	} else {
+		r = reflect.New(t)
+	}
+
+	if err := r.Interface().(sql.Scanner).Scan(v); err != nil {
+		return v, err
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return r.Interface(), nil
+	}
+
+	return r.Elem().Interface(), nil
+}
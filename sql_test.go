@@ -0,0 +1,55 @@
+package conf
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestSQLNullTypes(t *testing.T) {
+
+	type tConfOut struct {
+		Name    sql.NullString  `conf:"name"`
+		Comment sql.NullString  `conf:"comment"`
+		Limit   sql.NullInt64   `conf:"limit"`
+		Offset  sql.NullInt64   `conf:"offset"`
+		Timeout *sql.NullInt64  `conf:"timeout"`
+		Ratio   sql.NullFloat64 `conf:"ratio" conf_extraopts:"default=0.5"`
+	}
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, "name: \"\"\nlimit: 100\ntimeout: \"30\"\n", &c, Settings{}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	// Present values are valid even if empty
+	if c.Name.Valid != true || c.Name.String != "" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	if c.Limit.Valid != true || c.Limit.Int64 != 100 {
+		t.Fatal("Incorrect loaded data: Limit")
+	}
+
+	if c.Timeout == nil || c.Timeout.Valid != true || c.Timeout.Int64 != 30 {
+		t.Fatal("Incorrect loaded data: Timeout")
+	}
+
+	// Absent values are invalid
+	if c.Comment.Valid != false || c.Offset.Valid != false {
+		t.Fatal("Incorrect loaded data: absent options")
+	}
+
+	if c.Ratio.Valid != true || c.Ratio.Float64 != 0.5 {
+		t.Fatal("Incorrect loaded data: Ratio")
+	}
+
+	f := Flatten(&c)
+	if _, ok := f["comment"]; ok == true || f["limit"] != "100" {
+		t.Fatal("Incorrect flatten data:", f)
+	}
+
+	if err := testLoadYAML(t, "limit: abc\n", &tConfOut{}, Settings{}); err == nil {
+		t.Fatal("Expected error for invalid NullInt64 value")
+	}
+}
@@ -0,0 +1,122 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonSubtreeUnmarshal unmarshals JSON config data `cfgFile` into raw map containing only the subtree with path `path`
+// (and top-level include key if includes are enabled and config version key if `SchemaVersion` is set). Other values are skipped without decoding, so big configs are not materialized entirely
+func (s *Settings) jsonSubtreeUnmarshal(cfgFile []byte, path []string) (map[string]interface{}, error) {
+
+	dec := json.NewDecoder(bytes.NewReader(cfgFile))
+
+	v, err := s.jsonPrunedDecode(dec, path, true)
+	if err != nil {
+		return nil, jsonParseError(cfgFile, err)
+	}
+
+	// Config must contain a single top-level value
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("invalid data after top-level value")
+		}
+		return nil, jsonParseError(cfgFile, err)
+	}
+
+	m, ok := v.(map[string]interface{})
+	if ok == false {
+		// Config is not an object, so the error is reported the same way as for the whole config decoding
+		return confUnmarshal(cfgFile, ConfigTypeJSON)
+	}
+
+	return m, nil
+}
+
+// jsonPrunedDecode decodes the next JSON value from `dec` keeping only keys along path `path`
+// (and include and config version keys if `top` is true). Values of other keys are skipped
+func (s *Settings) jsonPrunedDecode(dec *json.Decoder, path []string, top bool) (interface{}, error) {
+
+	var v interface{}
+
+	if len(path) == 0 {
+		err := dec.Decode(&v)
+		return v, err
+	}
+
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case json.Delim('{'):
+	case json.Delim('['):
+		// Lists are not on the path, so they are skipped as a whole
+		return []interface{}{}, s.jsonSkip(dec)
+	default:
+		return t, nil
+	}
+
+	var skip json.RawMessage
+
+	m := make(map[string]interface{})
+
+	for dec.More() == true {
+
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		k, ok := t.(string)
+		if ok == false {
+			return nil, fmt.Errorf("unexpected token '%v'", t)
+		}
+
+		switch {
+		case k == path[0]:
+			v, err = s.jsonPrunedDecode(dec, path[1:], false)
+		case top == true && ((s.includes == true && k == s.includeKey()) || (s.SchemaVersion != nil && k == s.versionField())):
+			v = nil
+			err = dec.Decode(&v)
+		default:
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		m[k] = v
+	}
+
+	// Object closing delimiter
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// jsonSkip skips the rest of JSON list which opening delimiter is already read from `dec`
+func (s *Settings) jsonSkip(dec *json.Decoder) error {
+
+	var skip json.RawMessage
+
+	for dec.More() == true {
+		if err := dec.Decode(&skip); err != nil {
+			return err
+		}
+	}
+
+	// List closing delimiter
+	_, err := dec.Token()
+
+	return err
+}
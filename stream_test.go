@@ -0,0 +1,142 @@
+package conf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONSubtreeUnmarshal(t *testing.T) {
+
+	type tConfOut struct {
+		Listen  string   `conf:"listen" conf_extraopts:"required"`
+		Workers int      `conf:"workers" conf_extraopts:"default=4"`
+		Tags    []string `conf:"tags"`
+	}
+
+	d := `{
+  "services": {
+    "worker": {"queue": "jobs", "list": [1, [2, 3], {"a": null}]},
+    "api": {"listen": ":8080", "tags": ["a", "b"]},
+    "api.v2": {"listen": ":8081"}
+  },
+  "include": [],
+  "database": {"host": "localhost"}
+}`
+
+	// Include key is kept for includes resolving
+	s := Settings{includes: true}
+
+	r, err := s.jsonSubtreeUnmarshal([]byte(d), s.optPathSplit("services.api"))
+	if err != nil {
+		t.Fatal("Config unmarshal error:", err)
+	}
+
+	// Other values are skipped
+	e := map[string]interface{}{
+		"services": map[string]interface{}{
+			"api": map[string]interface{}{
+				"listen": ":8080",
+				"tags":   []interface{}{"a", "b"},
+			},
+		},
+		"include": []interface{}{},
+	}
+
+	if reflect.DeepEqual(r, e) == false {
+		t.Fatal("Incorrect unmarshalled data:", r)
+	}
+
+	var c tConfOut
+
+	p := filepath.Join(t.TempDir(), "conf.json")
+	if err := ioutil.WriteFile(p, []byte(d), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	if err := Load(&c, Settings{ConfPath: p, ConfType: ConfigTypeJSON, RootKey: `services.api\.v2`, UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Listen != ":8081" || c.Workers != 4 {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	// Syntax errors of skipped values are reported anyway
+	err = LoadBytes(&c, []byte("{\"other\": {\"a\": 1,},\n\"api\": {\"listen\": \":80\"}}"), Settings{ConfType: ConfigTypeJSON, RootKey: "api"})
+	if err == nil || strings.Contains(err.Error(), "invalid character") == false {
+		t.Fatal("Expected syntax error, got:", err)
+	}
+}
+
+func BenchmarkJSONSubtreeUnmarshal(b *testing.B) {
+
+	var sb strings.Builder
+
+	sb.WriteString(`{"services": {`)
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&sb, `"svc%d": {"listen": ":%d", "tags": ["a", "b", "c"], "opts": {"x": 1, "y": "z"}},`, i, i)
+	}
+	sb.WriteString(`"api": {"listen": ":8080"}}}`)
+
+	data := []byte(sb.String())
+
+	var s Settings
+
+	b.Run("full", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r, err := confUnmarshal(data, ConfigTypeJSON)
+			if err != nil {
+				b.Fatal("Config unmarshal error:", err)
+			}
+			if _, err := s.rawSubtreeGet(r, "services.api"); err != nil {
+				b.Fatal("Root key error:", err)
+			}
+		}
+	})
+
+	b.Run("subtree", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r, err := s.jsonSubtreeUnmarshal(data, []string{"services", "api"})
+			if err != nil {
+				b.Fatal("Config unmarshal error:", err)
+			}
+			if _, err := s.rawSubtreeGet(r, "services.api"); err != nil {
+				b.Fatal("Root key error:", err)
+			}
+		}
+	})
+}
+
+func TestJSONSubtreeTrailingData(t *testing.T) {
+
+	type tConfOut struct {
+		Listen string `conf:"listen"`
+	}
+
+	for _, d := range []string{
+		`{"svc": {"listen": ":8080"}} garbage`,
+		`{"svc": {"listen": ":8080"}} {}`,
+	} {
+		err := LoadBytes(&tConfOut{}, []byte(d), Settings{ConfType: ConfigTypeJSON, RootKey: "svc"})
+		if err == nil {
+			t.Fatalf("Expected error for trailing data of `%s`", d)
+		}
+	}
+
+	// Trailing whitespaces are allowed
+	var c tConfOut
+
+	if err := LoadBytes(&c, []byte("{\"svc\": {\"listen\": \":8080\"}}\n\n"), Settings{ConfType: ConfigTypeJSON, RootKey: "svc"}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Listen != ":8080" {
+		t.Fatal("Incorrect loaded data: Listen")
+	}
+}
@@ -0,0 +1,151 @@
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// types contains registered types of polymorphic options
+var types = struct {
+	sync.RWMutex
+	m map[string]reflect.Type
+}{
+	m: make(map[string]reflect.Type),
+}
+
+// RegisterType registers type of `proto` with `name`. Options of interface types with `discriminator`
+// extra option are decoded into registered type with name specified by discriminator value.
+// If `proto` is a pointer, options are set to pointers to decoded values
+func RegisterType(name string, proto interface{}) {
+
+	types.Lock()
+	defer types.Unlock()
+
+	types.m[name] = reflect.TypeOf(proto)
+}
+
+// typeGet gets registered type by `name`
+func typeGet(name string) (reflect.Type, bool) {
+
+	types.RLock()
+	defer types.RUnlock()
+
+	t, ok := types.m[name]
+	return t, ok
+}
+
+// resolveDiscriminators replaces raw value of interface option (or slice of interfaces) with `discriminator` extra option
+// with value of registered type selected by discriminator key within raw value
+func (s *Settings) resolveDiscriminators(m reflect.Value, k reflect.Value, tf reflect.StructField, elName string) error {
+
+	key, ok := s.tagValGet(tf.Tag.Get(tagConfExtraOptsName), tagConfDiscriminatorName)
+	if ok == false {
+		return nil
+	}
+
+	raw := m.MapIndex(k).Interface()
+	if raw == nil {
+		return nil
+	}
+
+	switch {
+	case tf.Type.Kind() == reflect.Interface:
+		v, err := s.discriminatedDecode(raw, tf.Type, key, elName)
+		if err != nil {
+			return err
+		}
+		m.SetMapIndex(k, v)
+	case tf.Type.Kind() == reflect.Slice && tf.Type.Elem().Kind() == reflect.Interface:
+		rv := reflect.ValueOf(raw)
+		if rv.Kind() != reflect.Slice {
+			return fmt.Errorf("option '%s' must be a list", elName)
+		}
+		l := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			v, err := s.discriminatedDecode(rv.Index(i).Interface(), tf.Type.Elem(), key, fmt.Sprintf("%s[%d]", elName, i))
+			if err != nil {
+				return err
+			}
+			l[i] = v.Interface()
+		}
+		m.SetMapIndex(k, reflect.ValueOf(l))
+	default:
+		return fmt.Errorf("option '%s' with `%s` must be an interface or a slice of interfaces", elName, tagConfDiscriminatorName)
+	}
+
+	return nil
+}
+
+// discriminatedDecode decodes raw map `raw` into value of registered type selected by value of `key` within `raw`.
+// Decoded value must implement interface `it`
+func (s *Settings) discriminatedDecode(raw interface{}, it reflect.Type, key string, elName string) (reflect.Value, error) {
+
+	rv := reflect.ValueOf(raw)
+	if rv.Kind() != reflect.Map {
+		return reflect.Value{}, fmt.Errorf("option '%s' must be a map", elName)
+	}
+
+	k, ok := s.rawMapKey(rv, key)
+	if ok == false {
+		return reflect.Value{}, fmt.Errorf("option '%s' discriminator '%s' is not specified", elName, key)
+	}
+
+	name := fmt.Sprint(rv.MapIndex(k).Interface())
+
+	t, ok := typeGet(name)
+	if ok == false {
+		return reflect.Value{}, fmt.Errorf("option '%s' type '%s' is not registered", elName, name)
+	}
+
+	if t.AssignableTo(it) == false {
+		return reflect.Value{}, fmt.Errorf("option '%s' type '%s' (`%s`) doesn't implement `%s`", elName, name, t, it)
+	}
+
+	// Discriminator is not an option of selected type
+	subRaw := make(map[string]interface{})
+	for _, e := range rv.MapKeys() {
+		if e.Interface() != k.Interface() {
+			subRaw[fmt.Sprint(e.Interface())] = rv.MapIndex(e).Interface()
+		}
+	}
+
+	p := reflect.New(t)
+	if t.Kind() == reflect.Ptr {
+		p.Elem().Set(reflect.New(t.Elem()))
+		p = p.Elem()
+	}
+
+	// Options are decoded with the same settings, but separate state
+	sub := *s
+	sub.md = mapstructure.Metadata{}
+	sub.used = nil
+	sub.nulls = nil
+	sub.rest = nil
+	sub.warnings = nil
+	sub.errsCollect = false
+	sub.errs = nil
+	sub.defaulted = nil
+	sub.sources = nil
+
+	if err := confDecode(p.Interface(), subRaw, &sub); err != nil {
+		return reflect.Value{}, fmt.Errorf("option '%s': %v", elName, strings.TrimPrefix(err.Error(), "config error: "))
+	}
+
+	s.warnings = append(s.warnings, sub.warnings...)
+	for _, d := range sub.defaulted {
+		s.defaulted = append(s.defaulted, elName+"."+d)
+	}
+	for p, k := range sub.sources {
+		s.sources[elName+"."+p] = k
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return p, nil
+	}
+
+	return p.Elem(), nil
+}
@@ -0,0 +1,150 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type tStorage interface {
+	Location() string
+}
+
+type tStorageS3 struct {
+	Bucket string `conf:"bucket" conf_extraopts:"required"`
+	Region string `conf:"region" conf_extraopts:"default=us-east-1"`
+}
+
+func (s *tStorageS3) Location() string {
+	return "s3://" + s.Bucket + "@" + s.Region
+}
+
+type tStorageLocal struct {
+	Path string `conf:"path"`
+}
+
+func (s tStorageLocal) Location() string {
+	return "file://" + s.Path
+}
+
+func TestDiscriminator(t *testing.T) {
+
+	RegisterType("s3", &tStorageS3{})
+	RegisterType("local", tStorageLocal{})
+
+	type tConfOut struct {
+		Storage  tStorage   `conf:"storage" conf_extraopts:"discriminator=type"`
+		Replicas []tStorage `conf:"replicas" conf_extraopts:"discriminator=type"`
+	}
+
+	d := `
+storage:
+  type: s3
+  bucket: data
+replicas:
+  - type: local
+    path: /backup
+  - type: s3
+    bucket: replica
+    region: eu-west-1
+`
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, d, &c, Settings{UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Storage == nil || c.Storage.Location() != "s3://data@us-east-1" {
+		t.Fatal("Incorrect loaded data: Storage")
+	}
+
+	if len(c.Replicas) != 2 || c.Replicas[0].Location() != "file:///backup" || c.Replicas[1].Location() != "s3://replica@eu-west-1" {
+		t.Fatal("Incorrect loaded data: Replicas")
+	}
+
+	tests := map[string]string{
+		"storage:\n  type: ftp\n":                "type 'ftp' is not registered",
+		"storage:\n  bucket: data\n":             "discriminator 'type' is not specified",
+		"storage:\n  type: s3\n":                 "required option 'bucket'",
+		"storage:\n  type: local\n  bucket: a\n": "unknown option",
+	}
+
+	for d, e := range tests {
+		err := testLoadYAML(t, d, &tConfOut{}, Settings{UnknownDeny: true})
+		if err == nil || strings.Contains(err.Error(), e) == false {
+			t.Fatalf("Incorrect error for `%s`: %v", d, err)
+		}
+	}
+}
+
+type tPlugin interface {
+	Name() string
+}
+
+type tPluginAuth struct {
+	Realm string `conf:"realm" conf_extraopts:"default=app"`
+}
+
+func (p tPluginAuth) Name() string {
+	return "auth:" + p.Realm
+}
+
+type tPluginRateLimit struct {
+	RPS int `conf:"rps" conf_extraopts:"required"`
+}
+
+func (p *tPluginRateLimit) Name() string {
+	return fmt.Sprintf("ratelimit:%d", p.RPS)
+}
+
+func TestDiscriminatorSlice(t *testing.T) {
+
+	RegisterType("plugin_auth", tPluginAuth{})
+	RegisterType("plugin_ratelimit", &tPluginRateLimit{})
+
+	type tConfOut struct {
+		Plugins []tPlugin `conf:"plugins" conf_extraopts:"discriminator=kind"`
+	}
+
+	d := `
+plugins:
+  - kind: plugin_ratelimit
+    rps: 100
+  - kind: plugin_auth
+  - kind: plugin_auth
+    realm: admin
+`
+
+	var c tConfOut
+
+	if err := testLoadYAML(t, d, &c, Settings{UnknownDeny: true}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if len(c.Plugins) != 3 {
+		t.Fatal("Incorrect loaded data: Plugins")
+	}
+
+	for i, n := range []string{"ratelimit:100", "auth:app", "auth:admin"} {
+		if c.Plugins[i].Name() != n {
+			t.Fatalf("Incorrect loaded data: Plugins[%d]: %s", i, c.Plugins[i].Name())
+		}
+	}
+
+	// Errors are reported with index of the element
+	tests := map[string]string{
+		"plugins:\n  - kind: plugin_auth\n  - kind: ftp\n":    "option 'plugins[1]' type 'ftp' is not registered",
+		"plugins:\n  - kind: plugin_auth\n  - realm: admin\n": "option 'plugins[1]' discriminator 'kind' is not specified",
+		"plugins:\n  - kind: plugin_ratelimit\n":              "option 'plugins[0]': required option 'rps'",
+		"plugins:\n  - kind: plugin_auth\n  - plugin_auth\n":  "option 'plugins[1]' must be a map",
+		"plugins:\n  - kind: plugin_auth\n    rps: 1\n":       "option 'plugins[0]': unknown option 'rps'",
+	}
+
+	for d, e := range tests {
+		err := testLoadYAML(t, d, &tConfOut{}, Settings{UnknownDeny: true})
+		if err == nil || strings.Contains(err.Error(), e) == false {
+			t.Fatalf("Incorrect error for `%s`: %v", d, err)
+		}
+	}
+}
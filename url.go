@@ -0,0 +1,82 @@
+package conf
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"time"
+)
+
+const urlTimeoutDefault = 10 * time.Second
+
+// LoadURL reads config from `url` with HTTP GET request. Settings field `ConfPath` is ignored.
+// With `ConfigTypeAuto` the format is detected by response `Content-Type` header
+func LoadURL(conf interface{}, url string, s Settings) error {
+	return LoadURLContext(context.Background(), conf, url, s)
+}
+
+// LoadURLContext reads config from `url` the same way as `LoadURL`. HTTP request is cancelled with context `ctx`
+func LoadURLContext(ctx context.Context, conf interface{}, url string, s Settings) error {
+
+	timeout := s.URLTimeout
+	if timeout == 0 {
+		timeout = urlTimeoutDefault
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	for k, v := range s.URLHeaders {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("config error: unable to get config from '%s': unexpected status %s", url, resp.Status)
+	}
+
+	cfgFile, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("config error: %v", err)
+	}
+
+	s.sourcePath = url
+
+	if s.ConfType == ConfigTypeAuto {
+		s.ConfType = confTypeByContentType(resp.Header.Get("Content-Type"))
+	}
+
+	return confRead(conf, cfgFile, &s)
+}
+
+// confTypeByContentType detects config type by HTTP `Content-Type` header value.
+// If content type is unknown `ConfigTypeAuto` is returned, so JSON and then YAML formats are tried
+func confTypeByContentType(contentType string) ConfigType {
+
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ConfigTypeAuto
+	}
+
+	switch t {
+	case "application/json":
+		return ConfigTypeJSON
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return ConfigTypeYAML
+	}
+
+	return ConfigTypeAuto
+}
@@ -0,0 +1,77 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadURL(t *testing.T) {
+
+	type tConfOut struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port"`
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write([]byte("host: example.com\nport: 8080\n"))
+	}))
+	defer srv.Close()
+
+	var c tConfOut
+
+	if err := LoadURL(&c, srv.URL, Settings{
+		ConfType:   ConfigTypeAuto,
+		URLHeaders: map[string]string{"X-Token": "secret"},
+	}); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Host != "example.com" || c.Port != 8080 {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	err := LoadURL(&c, srv.URL, Settings{ConfType: ConfigTypeAuto})
+	if err == nil || strings.Contains(err.Error(), "403") == false {
+		t.Fatal("Expected error with status code, got:", err)
+	}
+}
+
+func TestLoadURLContext(t *testing.T) {
+
+	type tConfOut struct {
+		Host string `conf:"host"`
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var c tConfOut
+
+	err := LoadURLContext(ctx, &c, srv.URL, Settings{ConfType: ConfigTypeYAML})
+	if errors.Is(err, context.DeadlineExceeded) == false {
+		t.Fatal("Expected context error, got:", err)
+	}
+}
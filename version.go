@@ -0,0 +1,83 @@
+package conf
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// versionFieldDefault is a default name of config file option containing config schema version
+const versionFieldDefault = "version"
+
+// VersionRange is a range of config schema versions supported by application
+type VersionRange struct {
+
+	// Min is a minimal supported version
+	Min int
+
+	// Max is a maximal supported version. Versions are not limited from above if it's zero
+	Max int
+}
+
+// checkVersion checks config schema version specified in top-level option `VersionField` of raw config `rawConf`
+// is within `SchemaVersion` range
+func (s *Settings) checkVersion(rawConf map[string]interface{}) error {
+
+	name := s.versionField()
+
+	v, ok := rawConf[name]
+	if ok == false || v == nil {
+		return fmt.Errorf("config version option '%s' is not specified", name)
+	}
+
+	ver, ok := s.versionParse(v)
+	if ok == false {
+		return fmt.Errorf("config version option '%s' has invalid value '%v'", name, v)
+	}
+
+	if ver < s.SchemaVersion.Min || (s.SchemaVersion.Max != 0 && ver > s.SchemaVersion.Max) {
+		if s.SchemaVersion.Max == 0 {
+			return fmt.Errorf("config version %d is not supported (supported versions: %d and above)", ver, s.SchemaVersion.Min)
+		}
+		return fmt.Errorf("config version %d is not supported (supported versions: %d-%d)", ver, s.SchemaVersion.Min, s.SchemaVersion.Max)
+	}
+
+	return nil
+}
+
+// versionField returns name of top-level option containing config schema version
+func (s *Settings) versionField() string {
+
+	if s.VersionField == "" {
+		return versionFieldDefault
+	}
+
+	return s.VersionField
+}
+
+// versionParse returns integer version from raw config value `v`
+func (s *Settings) versionParse(v interface{}) (int, bool) {
+
+	switch e := v.(type) {
+	case int:
+		return e, true
+	case int64:
+		return int(e), true
+	case uint64:
+		return int(e), true
+	case float64:
+		// JSON numbers are decoded as floats
+		if e != math.Trunc(e) {
+			return 0, false
+		}
+		return int(e), true
+	case string:
+		i, err := strconv.Atoi(e)
+		if err != nil {
+			return 0, false
+		}
+		return i, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,120 @@
+package conf
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersion(t *testing.T) {
+
+	type tConfOut struct {
+		Version int    `conf:"version"`
+		Name    string `conf:"name"`
+	}
+
+	var c tConfOut
+
+	s := Settings{
+		SchemaVersion: &VersionRange{Min: 2, Max: 3},
+	}
+
+	if err := testLoadYAML(t, "version: 2\nname: app\n", &c, s); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Version != 2 || c.Name != "app" {
+		t.Fatal("Incorrect loaded data")
+	}
+
+	// Versions specified as strings are accepted too
+	if err := testLoadYAML(t, "version: \"3\"\n", &c, s); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+}
+
+func TestSchemaVersionUnsupported(t *testing.T) {
+
+	type tConfOut struct {
+		Version int `conf:"version"`
+	}
+
+	var c tConfOut
+
+	s := Settings{
+		SchemaVersion: &VersionRange{Min: 2, Max: 3},
+	}
+
+	err := testLoadYAML(t, "version: 1\n", &c, s)
+	if err == nil || strings.Contains(err.Error(), "config version 1 is not supported (supported versions: 2-3)") == false {
+		t.Fatal("Expected unsupported version error, got:", err)
+	}
+
+	err = testLoadYAML(t, "version: 4\n", &c, s)
+	if err == nil || strings.Contains(err.Error(), "config version 4 is not supported") == false {
+		t.Fatal("Expected unsupported version error, got:", err)
+	}
+}
+
+func TestSchemaVersionMissing(t *testing.T) {
+
+	type tConfOut struct {
+		Version int    `conf:"schema"`
+		Name    string `conf:"name"`
+	}
+
+	var c tConfOut
+
+	s := Settings{
+		SchemaVersion: &VersionRange{Min: 1},
+		VersionField:  "schema",
+	}
+
+	err := testLoadYAML(t, "version: 1\nname: app\n", &c, s)
+	if err == nil || strings.Contains(err.Error(), "config version option 'schema' is not specified") == false {
+		t.Fatal("Expected missing version error, got:", err)
+	}
+
+	if err := testLoadYAML(t, "schema: 5\nname: app\n", &c, s); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+}
+
+func TestSchemaVersionRootKeyJSON(t *testing.T) {
+
+	type tConfOut struct {
+		Listen string `conf:"listen"`
+	}
+
+	var c tConfOut
+
+	s := Settings{
+		ConfPath:      filepath.Join(t.TempDir(), "conf.json"),
+		ConfType:      ConfigTypeJSON,
+		RootKey:       "services.api",
+		SchemaVersion: &VersionRange{Min: 2},
+	}
+
+	// Version is read from the top level of config rather than from the subtree
+	if err := ioutil.WriteFile(s.ConfPath, []byte(`{"version": 2, "services": {"api": {"listen": ":8080"}}}`), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	if err := Load(&c, s); err != nil {
+		t.Fatal("Config load error:", err)
+	}
+
+	if c.Listen != ":8080" {
+		t.Fatal("Incorrect loaded data: Listen")
+	}
+
+	if err := ioutil.WriteFile(s.ConfPath, []byte(`{"version": 1, "services": {"api": {"listen": ":8080"}}}`), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	err := Load(&c, s)
+	if err == nil || strings.Contains(err.Error(), "config version 1 is not supported") == false {
+		t.Fatal("Expected unsupported version error, got:", err)
+	}
+}
@@ -0,0 +1,216 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	watchIntervalDefault = time.Second
+)
+
+// Watcher watches config file for changes and reloads config.
+// Config file is polled with `WatchInterval` settings field period, reload is done
+// when file is changed and then is not modified for the next poll period (so rapid successive writes
+// produce a single reload). Every successful reload loads config into a fresh copy and delivers it
+// over `Updates` channel, failed reloads are delivered over `Errors` channel and the last good value is kept.
+// Channels keep only the latest value not read yet, so a caller may read either of them only.
+//
+// Polling is used instead of file system notifications (e.g. `fsnotify`) deliberately: it keeps the package
+// free of platform-specific dependencies and follows config files replaced by rename or symlink swap
+// (e.g. by editors or Kubernetes ConfigMap updates), for which notification watches on the file are lost.
+type Watcher struct {
+	s Settings
+	t reflect.Type
+
+	updates chan interface{}
+	errors  chan error
+	reload  chan struct{}
+	done    chan struct{}
+	close   sync.Once
+
+	mu    sync.RWMutex
+	value interface{}
+}
+
+type watchFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher loads config into `conf` and starts watching config file specified in settings.
+// Reloaded configs have the same type as `conf`
+func NewWatcher(conf interface{}, s Settings) (*Watcher, error) {
+
+	// Check `conf` is a pointer
+	if reflect.TypeOf(conf).Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("config load internal error: `conf` must be a pointer")
+	}
+
+	st, err := watchFileStateGet(s.ConfPath)
+	if err != nil {
+		return nil, fmt.Errorf("config error: %s", err)
+	}
+
+	if err := Load(conf, s); err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		s:       s,
+		t:       reflect.TypeOf(conf).Elem(),
+		updates: make(chan interface{}, 1),
+		errors:  make(chan error, 1),
+		reload:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		value:   conf,
+	}
+
+	go w.run(st)
+
+	return w, nil
+}
+
+// Updates returns channel delivering successfully reloaded configs.
+// Values are pointers to configs of the same type as `conf` passed to `NewWatcher`
+func (w *Watcher) Updates() <-chan interface{} {
+	return w.updates
+}
+
+// Errors returns channel delivering config reload errors
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Value returns the last successfully loaded config
+func (w *Watcher) Value() interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.value
+}
+
+// Reload requests config reload regardless of config file changes (e.g. on SIGHUP)
+func (w *Watcher) Reload() {
+	select {
+	case w.reload <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops watching. `Updates` and `Errors` channels are closed after that
+func (w *Watcher) Close() {
+	w.close.Do(func() {
+		close(w.done)
+	})
+}
+
+func (w *Watcher) run(last watchFileState) {
+
+	defer close(w.updates)
+	defer close(w.errors)
+
+	interval := w.s.WatchInterval
+	if interval <= 0 {
+		interval = watchIntervalDefault
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := false
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.reload:
+			w.load()
+		case <-ticker.C:
+
+			// File may be temporary absent while it's being replaced
+			st, err := watchFileStateGet(w.s.ConfPath)
+			if err != nil {
+				continue
+			}
+
+			// Wait for file is not modified within the poll period
+			if st != last {
+				last = st
+				pending = true
+				continue
+			}
+
+			if pending == true {
+				pending = false
+				w.load()
+			}
+		}
+	}
+}
+
+// load loads config into a fresh copy and delivers the result
+func (w *Watcher) load() {
+
+	v := reflect.New(w.t).Interface()
+
+	if err := Load(v, w.s); err != nil {
+		w.errorSend(err)
+		return
+	}
+
+	w.mu.Lock()
+	w.value = v
+	w.mu.Unlock()
+
+	w.updateSend(v)
+}
+
+// updateSend delivers reloaded config `v` replacing the previous one if it's not read yet
+func (w *Watcher) updateSend(v interface{}) {
+
+	for {
+		select {
+		case w.updates <- v:
+			return
+		default:
+		}
+
+		select {
+		case <-w.updates:
+		default:
+		}
+	}
+}
+
+// errorSend delivers reload error `err` replacing the previous one if it's not read yet
+func (w *Watcher) errorSend(err error) {
+
+	for {
+		select {
+		case w.errors <- err:
+			return
+		default:
+		}
+
+		select {
+		case <-w.errors:
+		default:
+		}
+	}
+}
+
+func watchFileStateGet(path string) (watchFileState, error) {
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return watchFileState{}, err
+	}
+
+	return watchFileState{
+		modTime: fi.ModTime(),
+		size:    fi.Size(),
+	}, nil
+}
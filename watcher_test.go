@@ -0,0 +1,119 @@
+package conf
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+
+	type tConfOut struct {
+		Name string `conf:"name" conf_extraopts:"required"`
+	}
+
+	p := filepath.Join(t.TempDir(), "conf.yml")
+
+	if err := ioutil.WriteFile(p, []byte("name: first\n"), 0644); err != nil {
+		t.Fatal("Config file prepare error:", err)
+	}
+
+	var c tConfOut
+
+	w, err := NewWatcher(&c, Settings{
+		ConfPath:      p,
+		ConfType:      ConfigTypeYAML,
+		WatchInterval: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal("Watcher create error:", err)
+	}
+	defer w.Close()
+
+	if c.Name != "first" {
+		t.Fatal("Incorrect loaded data: Name")
+	}
+
+	// Check config reload on file change
+	if err := ioutil.WriteFile(p, []byte("name: second\n"), 0644); err != nil {
+		t.Fatal("Config file write error:", err)
+	}
+
+	select {
+	case v := <-w.Updates():
+		if v.(*tConfOut).Name != "second" {
+			t.Fatal("Incorrect reloaded data: Name")
+		}
+	case err := <-w.Errors():
+		t.Fatal("Config reload error:", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Config reload timeout")
+	}
+
+	// Check failed reload keeps the last good value
+	if err := ioutil.WriteFile(p, []byte("{}\n"), 0644); err != nil {
+		t.Fatal("Config file write error:", err)
+	}
+
+	select {
+	case <-w.Updates():
+		t.Fatal("Unexpected config reload")
+	case <-w.Errors():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Config reload timeout")
+	}
+
+	if w.Value().(*tConfOut).Name != "second" {
+		t.Fatal("Incorrect last good value")
+	}
+
+	// Check explicit reload
+	if err := ioutil.WriteFile(p, []byte("name: third\n"), 0644); err != nil {
+		t.Fatal("Config file write error:", err)
+	}
+
+	w.Reload()
+
+	select {
+	case v := <-w.Updates():
+		if v.(*tConfOut).Name != "third" {
+			t.Fatal("Incorrect reloaded data: Name")
+		}
+	case err := <-w.Errors():
+		t.Fatal("Config reload error:", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Config reload timeout")
+	}
+
+	// Check unread errors don't block reloads
+	if err := ioutil.WriteFile(p, []byte("{}\n"), 0644); err != nil {
+		t.Fatal("Config file write error:", err)
+	}
+
+	w.Reload()
+	w.Reload()
+
+	time.Sleep(100 * time.Millisecond)
+
+	if err := ioutil.WriteFile(p, []byte("name: fourth\n"), 0644); err != nil {
+		t.Fatal("Config file write error:", err)
+	}
+
+	w.Reload()
+
+	select {
+	case v := <-w.Updates():
+		if v.(*tConfOut).Name != "fourth" {
+			t.Fatal("Incorrect reloaded data: Name")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Config reload timeout")
+	}
+
+	// Check channels are closed after watcher close
+	w.Close()
+
+	for range w.Updates() {
+	}
+}